@@ -1,17 +1,28 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"log"
 	"minecrat_go/cmd/database"
 	"minecrat_go/cmd/route"
+	"minecrat_go/helper/middleware"
+	"minecrat_go/helper/utils"
 	"minecrat_go/internal/handler"
 	"minecrat_go/internal/repository"
 	"minecrat_go/internal/usecase"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
 )
 
 func main() {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	db, err := database.ConnectDB()
 	if err != nil {
 		log.Fatal(err)
@@ -22,7 +33,27 @@ func main() {
 	authHandler := handler.NewAuthHandler(authUc)
 
 	bedrockRepo := repository.NewBedrockRepo(db)
-	bedrockUC := usecase.NewBedrockUC(bedrockRepo)
+	runtime := usecase.RuntimeRouter{
+		usecase.RuntimeProcess:   usecase.NewExecRuntime(),
+		usecase.RuntimeNamespace: usecase.NewNamespaceRuntime(),
+		usecase.RuntimeOCI:       usecase.NewOCIRuntime(os.Getenv("BEDROCK_OCI_ENGINE"), os.Getenv("BEDROCK_OCI_RUNTIME"), os.Getenv("BEDROCK_OCI_IMAGE")),
+	}
+	if os.Getenv("BEDROCK_RUNTIME") == "fake" {
+		log.Println("memakai fake runtime, bedrock_server tidak dijalankan")
+		fake := usecase.NewFakeRuntime()
+		for name := range runtime {
+			runtime[name] = fake
+		}
+	}
+
+	bedrockUC := usecase.NewBedrockUC(bedrockRepo, runtime)
+	if err := bedrockUC.Reconcile(); err != nil {
+		log.Printf("reconcile servers err :%s", err)
+	}
+	if err := bedrockUC.LoadSchedules(); err != nil {
+		log.Printf("load schedules err :%s", err)
+	}
+	go bedrockUC.StartAutostart(ctx)
 	bedrockHandler := handler.NewBedrockHandler(bedrockUC)
 
 	r := route.SetupRoute(authHandler, bedrockHandler)
@@ -32,7 +63,27 @@ func main() {
 		port = "8080"
 	}
 
-	log.Printf("server berjaalan pada port :%s", port)
-	http.ListenAndServe(":"+port, r)
+	var draining atomic.Bool
+	srv := &http.Server{Addr: ":" + port, Handler: middleware.Drain(r, &draining)}
+
+	go func() {
+		log.Printf("server berjaalan pada port :%s", port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal(err)
+		}
+	}()
+
+	<-ctx.Done()
+	stop()
+	log.Println("shutdown: menghentikan semua server bedrock")
 
+	draining.Store(true)
+	bedrockUC.StopAll()
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), utils.GetEnvDuration("SHUTDOWN_TIMEOUT", 10*time.Second))
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("shutdown http server err :%s", err)
+	}
+	log.Println("shutdown selesai")
 }
@@ -23,22 +23,69 @@ func SetupRoute(authHandler *handler.AuthHandler, bedrockHandler *handler.Bedroc
 	bedrockRoute.Use(middleware.AuthMiddeware)
 
 	bedrockRoute.HandleFunc("/create", bedrockHandler.CreateWorld).Methods(http.MethodPost)
+	// fixed paths go before /{world}/..., ValidateReq keeps worlds from
+	// taking their names
+	bedrockRoute.HandleFunc("/players", bedrockHandler.GetPlayers).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/players/{player}", bedrockHandler.GetPlayer).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/players/{player}/moderation", bedrockHandler.GetModerationHistory).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/properties/schema", bedrockHandler.GetPropertySchema).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/bans", bedrockHandler.GetBans).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/bans", bedrockHandler.CreateBan).Methods(http.MethodPost)
+	bedrockRoute.HandleFunc("/bans/temp", bedrockHandler.TempBan).Methods(http.MethodPost)
+	bedrockRoute.HandleFunc("/bans/{id:[0-9]+}", bedrockHandler.GetBan).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/bans/{id:[0-9]+}", bedrockHandler.Unban).Methods(http.MethodDelete)
 	bedrockRoute.HandleFunc("/{world}/delete", bedrockHandler.DeleteWorld).Methods(http.MethodDelete)
 	bedrockRoute.HandleFunc("/{world}/{id}/update", bedrockHandler.EditWorld).Methods(http.MethodPut)
 	bedrockRoute.HandleFunc("/start", bedrockHandler.StartWorld).Methods(http.MethodPost)
 	bedrockRoute.HandleFunc("/{world}/stop", bedrockHandler.StopWorld).Methods(http.MethodPost)
+	bedrockRoute.HandleFunc("/{world}/status", bedrockHandler.GetServerStatus).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/{world}/resources", bedrockHandler.GetResourceUsage).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/{world}/properties", bedrockHandler.GetProperties).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/{world}/properties", bedrockHandler.PatchProperties).Methods(http.MethodPatch)
+	bedrockRoute.HandleFunc("/{world}/schedules", bedrockHandler.GetSchedules).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/{world}/schedules", bedrockHandler.CreateSchedule).Methods(http.MethodPost)
+	bedrockRoute.HandleFunc("/{world}/schedules/{id}", bedrockHandler.UpdateSchedule).Methods(http.MethodPut)
+	bedrockRoute.HandleFunc("/{world}/schedules/{id}", bedrockHandler.DeleteSchedule).Methods(http.MethodDelete)
 	bedrockRoute.HandleFunc("/{world}/command", bedrockHandler.SendCommand).Methods(http.MethodPost)
 	bedrockRoute.HandleFunc("/{world}/command/ban/{name}", bedrockHandler.BanPlayer).Methods(http.MethodPost)
 	bedrockRoute.HandleFunc("/{world}/command/kick/{name}", bedrockHandler.KickPlayer).Methods(http.MethodPost)
+	bedrockRoute.HandleFunc("/{world}/moderation/warn", bedrockHandler.WarnPlayer).Methods(http.MethodPost)
+	bedrockRoute.HandleFunc("/{world}/moderation/mute", bedrockHandler.MutePlayer).Methods(http.MethodPost)
+	bedrockRoute.HandleFunc("/{world}/moderation/unmute", bedrockHandler.UnmutePlayer).Methods(http.MethodPost)
+	bedrockRoute.HandleFunc("/{world}/moderation/kick", bedrockHandler.ModerationKick).Methods(http.MethodPost)
 	bedrockRoute.HandleFunc("/{world}/get-permission-players", bedrockHandler.GetPermissionPlayer).Methods(http.MethodGet)
 	bedrockRoute.HandleFunc("/get-worlds", bedrockHandler.GetWorlds).Methods(http.MethodGet)
 	bedrockRoute.HandleFunc("/{world}/get-world-players", bedrockHandler.GetWorldAndPlayers).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/{world}/players/online", bedrockHandler.GetOnlinePlayers).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/{world}/players/playtime", bedrockHandler.GetPlaytime).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/{world}/players/{xuid}/sessions", bedrockHandler.GetPlayerSessions).Methods(http.MethodGet)
 	bedrockRoute.HandleFunc("/{world}/create-or-update-permission", bedrockHandler.CreateOrUpdatePermissionPlayer).Methods(http.MethodPost)
 	bedrockRoute.HandleFunc("/{world}/delete-permission/{xuid}", bedrockHandler.DeletePermissionPlayer).Methods(http.MethodDelete)
 	bedrockRoute.HandleFunc("/{world}/logs", bedrockHandler.GetLogsServer).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/{world}/logs/history", bedrockHandler.GetLogHistory).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/{world}/logs/files", bedrockHandler.GetLogFiles).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/{world}/logs/files/{file}", bedrockHandler.DownloadLogFile).Methods(http.MethodGet)
 	bedrockRoute.HandleFunc("/{world}/create-priority", bedrockHandler.CreatePriority).Methods(http.MethodPost)
 	bedrockRoute.HandleFunc("/{world}/delete-priority/{xuid}", bedrockHandler.DeletePriority).Methods(http.MethodDelete)
 	bedrockRoute.HandleFunc("/{world}/get-priority/", bedrockHandler.GetPriority).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/{world}/allowlist", bedrockHandler.GetAllowlist).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/{world}/allowlist", bedrockHandler.AddToAllowlist).Methods(http.MethodPost)
+	bedrockRoute.HandleFunc("/{world}/allowlist", bedrockHandler.ReplaceAllowlist).Methods(http.MethodPut)
+	bedrockRoute.HandleFunc("/{world}/allowlist/enable", bedrockHandler.EnableAllowlist).Methods(http.MethodPost)
+	bedrockRoute.HandleFunc("/{world}/allowlist/disable", bedrockHandler.DisableAllowlist).Methods(http.MethodPost)
+	bedrockRoute.HandleFunc("/{world}/allowlist/copy", bedrockHandler.CopyAllowlist).Methods(http.MethodPost)
+	bedrockRoute.HandleFunc("/{world}/allowlist/{player}", bedrockHandler.RemoveFromAllowlist).Methods(http.MethodDelete)
+	bedrockRoute.HandleFunc("/{world}/access/drift", bedrockHandler.GetAccessDrift).Methods(http.MethodGet)
+	bedrockRoute.HandleFunc("/{world}/access/reconcile", bedrockHandler.ReconcileAccess).Methods(http.MethodPost)
+
+	// the streaming routes alone accept ?token=, they come after the others
+	// so /players/events stays a player
+	streamRoute := r.PathPrefix("/bedrock").Subrouter()
+	streamRoute.Use(middleware.StreamAuthMiddeware)
+
+	streamRoute.HandleFunc("/events", bedrockHandler.Events).Methods(http.MethodGet)
+	streamRoute.HandleFunc("/{world}/console", bedrockHandler.Console).Methods(http.MethodGet)
+	streamRoute.HandleFunc("/{world}/events", bedrockHandler.WorldEvents).Methods(http.MethodGet)
 
 	return r
 }
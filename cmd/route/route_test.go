@@ -0,0 +1,100 @@
+package route
+
+import (
+	"minecrat_go/helper/utils"
+	"minecrat_go/internal/handler"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestQueryToken(t *testing.T) {
+	token, err := utils.GenerateJWTLogin(1, "admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := SetupRoute(handler.NewAuthHandler(nil), handler.NewBedrockHandler(nil))
+
+	tests := []struct {
+		name   string
+		url    string
+		header bool
+		want   int
+	}{
+		// an invalid history is refused by the handler, so reaching 400
+		// means the token was accepted
+		{"console takes the query token", "/bedrock/alpha/console?history=-1&token=" + token, false, http.StatusBadRequest},
+		{"console takes the header", "/bedrock/alpha/console?history=-1", true, http.StatusBadRequest},
+		{"console without token", "/bedrock/alpha/console?history=-1", false, http.StatusUnauthorized},
+		{"other routes ignore the query token", "/bedrock/get-worlds?token=" + token, false, http.StatusUnauthorized},
+		{"player routes ignore the query token", "/bedrock/players?token=" + token, false, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.url, nil)
+			if tt.header {
+				req.Header.Set("Authorization", "Bearer "+token)
+			}
+			rec := httptest.NewRecorder()
+			r.ServeHTTP(rec, req)
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRouteOrder(t *testing.T) {
+	r := SetupRoute(handler.NewAuthHandler(nil), handler.NewBedrockHandler(nil))
+
+	tests := []struct {
+		method string
+		path   string
+		want   string
+	}{
+		{http.MethodGet, "/bedrock/players/Steve", "/bedrock/players/{player}"},
+		{http.MethodGet, "/bedrock/players/Steve/moderation", "/bedrock/players/{player}/moderation"},
+		{http.MethodGet, "/bedrock/bans/12", "/bedrock/bans/{id:[0-9]+}"},
+		{http.MethodPost, "/bedrock/bans/temp", "/bedrock/bans/temp"},
+		{http.MethodGet, "/bedrock/properties/schema", "/bedrock/properties/schema"},
+		{http.MethodGet, "/bedrock/alpha/logs", "/bedrock/{world}/logs"},
+		{http.MethodGet, "/bedrock/alpha/console", "/bedrock/{world}/console"},
+		{http.MethodGet, "/bedrock/alpha/events", "/bedrock/{world}/events"},
+		{http.MethodGet, "/bedrock/events", "/bedrock/events"},
+	}
+
+	for _, tt := range tests {
+		var match mux.RouteMatch
+		if !r.Match(httptest.NewRequest(tt.method, tt.path, nil), &match) {
+			t.Errorf("%s %s matched no route", tt.method, tt.path)
+			continue
+		}
+		if got, _ := match.Route.GetPathTemplate(); got != tt.want {
+			t.Errorf("%s %s matched %s, want %s", tt.method, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestReservedWorldNames(t *testing.T) {
+	token, err := utils.GenerateJWTLogin(1, "admin@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := SetupRoute(handler.NewAuthHandler(nil), handler.NewBedrockHandler(nil))
+
+	// a world named players would lose /players/{player} to the directory
+	for _, name := range []string{"players", "Players", "bans", "events", "get-worlds"} {
+		body := `{"name":"` + name + `","game_mode":"survival","difficult":"easy","permission_player":"member"}`
+		req := httptest.NewRequest(http.MethodPost, "/bedrock/create", strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+token)
+		rec := httptest.NewRecorder()
+		r.ServeHTTP(rec, req)
+		if rec.Code != http.StatusBadRequest || !strings.Contains(rec.Body.String(), "dipakai") {
+			t.Errorf("create %q: status = %d %s, want the name refused", name, rec.Code, rec.Body)
+		}
+	}
+}
@@ -1,5 +1,7 @@
 package dto
 
+import "time"
+
 type Register struct {
 	Username string `json:"username"`
 	Email    string `json:"email"`
@@ -14,19 +16,51 @@ type ServerParams struct {
 	Creator                 uint   `json:"-"`
 	Name                    string `json:"name"`
 	Port                    int    `json:"port"`
+	PortV6                  int    `json:"port_v6"`
 	GameMode                string `json:"game_mode"`
 	Difficult               string `json:"difficult"`
-	AllowCheat              bool   `json:"allow_cheats"`
+	AllowCheat              *bool  `json:"allow_cheats"`
 	ViewDistance            int    `json:"view_distance"`
 	SeedWorld               string `json:"seed"`
 	MaxPlayer               int    `json:"max_player"`
 	DefaultPermissionPlayer string `json:"permission_player"`
+	RestartPolicy           string `json:"restart_policy"`
+	RestartMaxRetries       int    `json:"restart_max_retries"`
+	Autostart               *bool  `json:"autostart"`
+	StartOrder              int    `json:"start_order"`
+	Runtime                 string `json:"runtime"`
+	CPUQuota                int    `json:"cpu_quota"`
+	MemoryMaxMB             int    `json:"memory_max_mb"`
+	PidsMax                 int    `json:"pids_max"`
+	IdleShutdownMinutes     int    `json:"idle_shutdown_minutes"`
+	// Gamerules are set on the running world, e.g. {"keepInventory": true}.
+	Gamerules map[string]interface{} `json:"gamerules"`
+	// Restart is set from the restart query parameter, it restarts a running
+	// world when an edit cannot be applied live.
+	Restart bool `json:"-"`
 }
 
 type StartServerReq struct {
 	Name    string `json:"name"`
 	WorldId uint   `json:"world_id"`
 	Port    int    `json:"port"`
+	PortV6  int    `json:"port_v6"`
+	// Timeout in seconds to wait for the server to become ready, 0 uses
+	// BEDROCK_START_TIMEOUT.
+	Timeout int `json:"timeout"`
+	// Wait is set from the wait query parameter, without it StartServer
+	// returns as soon as the process is spawned.
+	Wait bool `json:"-"`
+}
+
+type StartServerResult struct {
+	Name     string `json:"name"`
+	State    string `json:"state"`
+	Ready    bool   `json:"ready"`
+	Version  string `json:"version,omitempty"`
+	PortV4   int    `json:"port_v4,omitempty"`
+	PortV6   int    `json:"port_v6,omitempty"`
+	Duration string `json:"duration"`
 }
 
 type PermissionPlayer struct {
@@ -40,12 +74,68 @@ type Allowlist struct {
 	Priority bool   `json:"ignoresPlayerLimit"`
 }
 
+type AllowlistState struct {
+	World   string      `json:"world"`
+	Enabled bool        `json:"enabled"`
+	Entries []Allowlist `json:"entries"`
+}
+
+type AllowlistCopyReq struct {
+	From string `json:"from"`
+}
+
+// AllowlistImport is the allowlist a bulk replace or copy wrote, with the
+// entries it left out.
+type AllowlistImport struct {
+	Entries []Allowlist     `json:"entries"`
+	Skipped []AllowlistSkip `json:"skipped"`
+}
+
+type AllowlistSkip struct {
+	Entry  Allowlist `json:"entry"`
+	Reason string    `json:"reason"`
+}
+
+// AccessDrift lists where permissions.json and allowlist.json of a world
+// differ from the database, an entry changed by hand shows up on both sides.
+type AccessDrift struct {
+	World       string          `json:"world"`
+	InSync      bool            `json:"in_sync"`
+	Permissions PermissionDrift `json:"permissions"`
+	Allowlist   AllowlistDrift  `json:"allowlist"`
+}
+
+type PermissionDrift struct {
+	OnlyInDB   []PermissionPlayer `json:"only_in_db"`
+	OnlyInFile []PermissionPlayer `json:"only_in_file"`
+	// Error is set when the file cannot be read.
+	Error string `json:"error,omitempty"`
+}
+
+type AllowlistDrift struct {
+	OnlyInDB   []Allowlist `json:"only_in_db"`
+	OnlyInFile []Allowlist `json:"only_in_file"`
+	Error      string      `json:"error,omitempty"`
+}
+
+// PlayerAccess is what a player has on one world.
+type PlayerAccess struct {
+	World              string `json:"world"`
+	Permission         string `json:"permission,omitempty"`
+	Allowlisted        bool   `json:"allowlisted"`
+	IgnoresPlayerLimit bool   `json:"ignoresPlayerLimit"`
+}
+
 type GetWorlds struct {
 	ID      uint   `json:"id"`
 	Creator string `json:"creator"`
 	Name    string `json:"name"`
 	Port    int    `json:"port"`
+	PortV6  int    `json:"port_v6"`
 	Players int    `json:"players"`
+
+	State      string     `json:"state"`
+	StateSince *time.Time `json:"state_since,omitempty"`
 }
 
 type GetWorldAndPlayers struct {
@@ -53,6 +143,7 @@ type GetWorldAndPlayers struct {
 	Creator                 string   `json:"creator"`
 	Name                    string   `json:"name"`
 	Port                    int      `json:"port"`
+	PortV6                  int      `json:"port_v6"`
 	GameMode                string   `json:"game_mode"`
 	Difficult               string   `json:"difficult"`
 	AllowCheat              bool     `json:"allow_cheats"`
@@ -61,8 +152,346 @@ type GetWorldAndPlayers struct {
 	MaxPlayer               int      `json:"max_player"`
 	DefaultPermissionPlayer string   `json:"permission_player"`
 	Players                 []Player `json:"players"`
+
+	State      string     `json:"state"`
+	StateSince *time.Time `json:"state_since,omitempty"`
 }
 
 type Player struct {
 	Xuid string `json:"xuid"`
+	Name string `json:"name,omitempty"`
+}
+
+type CommandReq struct {
+	CMD string `json:"cmd"`
+	// Timeout in seconds to wait for the output, 0 uses BEDROCK_COMMAND_TIMEOUT.
+	Timeout int `json:"timeout"`
+}
+
+type CommandResult struct {
+	Command  string   `json:"command"`
+	Lines    []string `json:"lines"`
+	TimedOut bool     `json:"timed_out"`
+	Duration string   `json:"duration"`
+}
+
+type StopServerReq struct {
+	Timeout int `json:"timeout"`
+}
+
+const (
+	StopMethodCommand = "stop"
+	StopMethodTerm    = "sigterm"
+	StopMethodKill    = "sigkill"
+
+	// StopMethodCancelRestart means the server was already down and only a
+	// pending automatic restart was cancelled.
+	StopMethodCancelRestart = "cancel-restart"
+)
+
+type StopServerResult struct {
+	Name     string `json:"name"`
+	Method   string `json:"method"`
+	ExitCode int    `json:"exit_code"`
+	Duration string `json:"duration"`
+}
+
+const ExitReasonOOM = "oom-killed"
+
+type ExitInfo struct {
+	Code      int       `json:"code"`
+	Expected  bool      `json:"expected"`
+	Reason    string    `json:"reason,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	At        time.Time `json:"at"`
+	LastLines []string  `json:"last_lines"`
+}
+
+type ServerStatus struct {
+	Name          string     `json:"name"`
+	State         string     `json:"state"`
+	StateSince    *time.Time `json:"state_since,omitempty"`
+	Running       bool       `json:"running"`
+	RestartPolicy string     `json:"restart_policy"`
+	Restarts      int        `json:"restarts"`
+	MaxRetries    int        `json:"max_retries"`
+	CrashLoop     bool       `json:"crash_loop"`
+	PlayersOnline int        `json:"players_online"`
+	Version       string     `json:"version,omitempty"`
+	PortV4        int        `json:"port_v4,omitempty"`
+	PortV6        int        `json:"port_v6,omitempty"`
+	NextRestartAt *time.Time `json:"next_restart_at,omitempty"`
+	LastExit      *ExitInfo  `json:"last_exit,omitempty"`
+	// PendingRestart lists the server.properties keys the running process
+	// does not have yet.
+	PendingRestart []string `json:"pending_restart,omitempty"`
+}
+
+type ResourceLimits struct {
+	CPUQuota    int `json:"cpu_quota"`
+	MemoryMaxMB int `json:"memory_max_mb"`
+	PidsMax     int `json:"pids_max"`
+}
+
+type ResourceUsage struct {
+	Name                string         `json:"name"`
+	Limits              ResourceLimits `json:"limits"`
+	Available           bool           `json:"available"`
+	CPUUsageSeconds     float64        `json:"cpu_usage_seconds"`
+	CPUThrottledSeconds float64        `json:"cpu_throttled_seconds"`
+	MemoryBytes         int64          `json:"memory_bytes"`
+	MemoryPeakBytes     int64          `json:"memory_peak_bytes"`
+	Pids                int64          `json:"pids"`
+	OOMKills            int64          `json:"oom_kills"`
+}
+
+type ScheduleReq struct {
+	Action      string `json:"action"`
+	Cron        string `json:"cron"`
+	WarnSeconds *int   `json:"warn_seconds"`
+	Enabled     *bool  `json:"enabled"`
+}
+
+type Schedule struct {
+	ID          uint       `json:"id"`
+	World       string     `json:"world"`
+	Action      string     `json:"action"`
+	Cron        string     `json:"cron"`
+	WarnSeconds int        `json:"warn_seconds"`
+	Enabled     bool       `json:"enabled"`
+	NextRunAt   *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt   *time.Time `json:"last_run_at,omitempty"`
+	LastError   string     `json:"last_error,omitempty"`
+}
+
+const (
+	EventLog          = "log"
+	EventPlayerJoined = "player_joined"
+	EventPlayerLeft   = "player_left"
+	EventStateChange  = "state_change"
+	EventCrash        = "crash"
+	EventAccessDrift  = "access_drift"
+)
+
+type Event struct {
+	ID    uint64      `json:"id"`
+	World string      `json:"world"`
+	Type  string      `json:"type"`
+	Time  time.Time   `json:"time"`
+	Data  interface{} `json:"data,omitempty"`
+}
+
+type LogEvent struct {
+	Line  string `json:"line"`
+	Level string `json:"level,omitempty"`
+	Kind  string `json:"kind"`
+}
+
+type PlayerEvent struct {
+	Name string `json:"name"`
+	Xuid string `json:"xuid"`
+}
+
+type StateEvent struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+type LogQuery struct {
+	From   time.Time
+	To     time.Time
+	Query  string
+	Regex  string
+	Offset int
+	Limit  int
+}
+
+type LogLine struct {
+	Time time.Time `json:"time"`
+	Line string    `json:"line"`
+}
+
+type LogPage struct {
+	Lines   []LogLine `json:"lines"`
+	Total   int       `json:"total"`
+	Offset  int       `json:"offset"`
+	Limit   int       `json:"limit"`
+	HasMore bool      `json:"has_more"`
+}
+
+type LogFile struct {
+	Name       string    `json:"name"`
+	Size       int64     `json:"size"`
+	ModTime    time.Time `json:"mod_time"`
+	Compressed bool      `json:"compressed"`
+}
+
+type OnlinePlayer struct {
+	Xuid     string    `json:"xuid"`
+	Name     string    `json:"name"`
+	JoinedAt time.Time `json:"joined_at"`
+	Seconds  int64     `json:"seconds"`
+}
+
+type PlayerSession struct {
+	ID        uint       `json:"id"`
+	Xuid      string     `json:"xuid"`
+	Name      string     `json:"name"`
+	JoinedAt  time.Time  `json:"joined_at"`
+	LeftAt    *time.Time `json:"left_at"`
+	Seconds   int64      `json:"seconds"`
+	EndReason string     `json:"end_reason,omitempty"`
+}
+
+type SessionPage struct {
+	Sessions []PlayerSession `json:"sessions"`
+	Total    int64           `json:"total"`
+	Offset   int             `json:"offset"`
+	Limit    int             `json:"limit"`
+}
+
+type Playtime struct {
+	Xuid     string    `json:"xuid"`
+	Name     string    `json:"name"`
+	Sessions int64     `json:"sessions"`
+	Seconds  int64     `json:"seconds"`
+	LastSeen time.Time `json:"last_seen"`
+	Online   bool      `json:"online"`
+}
+
+type PlayerProfile struct {
+	Xuid      string        `json:"xuid"`
+	Gamertag  string        `json:"gamertag"`
+	FirstSeen time.Time     `json:"first_seen"`
+	LastSeen  time.Time     `json:"last_seen"`
+	Names     []GamertagUse `json:"names,omitempty"`
+	// Online lists the worlds the player is connected to right now.
+	Online []string `json:"online"`
+	// Access lists the worlds the player has a permission or an allowlist
+	// entry on.
+	Access []PlayerAccess `json:"access"`
+}
+
+type GamertagUse struct {
+	Gamertag  string    `json:"gamertag"`
+	FirstSeen time.Time `json:"first_seen"`
+	LastSeen  time.Time `json:"last_seen"`
+}
+
+type PlayerPage struct {
+	Players []PlayerProfile `json:"players"`
+	Total   int64           `json:"total"`
+	Offset  int             `json:"offset"`
+	Limit   int             `json:"limit"`
+}
+
+type BanReq struct {
+	// Player is an xuid or a gamertag.
+	Player string `json:"player"`
+	// World limits the ban to one world, empty bans on every world.
+	World  string `json:"world"`
+	Reason string `json:"reason"`
+	// Duration makes a temporary ban, e.g. "90m", "72h" or "7d".
+	Duration string `json:"duration"`
+
+	ActorId uint   `json:"-"`
+	Actor   string `json:"-"`
+}
+
+type UnbanReq struct {
+	Reason string `json:"reason"`
+
+	ActorId uint   `json:"-"`
+	Actor   string `json:"-"`
+}
+
+type Ban struct {
+	ID         uint       `json:"id"`
+	Xuid       string     `json:"xuid"`
+	Gamertag   string     `json:"gamertag"`
+	World      string     `json:"world,omitempty"`
+	Reason     string     `json:"reason"`
+	IssuedBy   string     `json:"issued_by"`
+	CreatedAt  time.Time  `json:"created_at"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LiftedAt   *time.Time `json:"lifted_at,omitempty"`
+	LiftedBy   string     `json:"lifted_by,omitempty"`
+	LiftReason string     `json:"lift_reason,omitempty"`
+	Active     bool       `json:"active"`
+	Audit      []BanAudit `json:"audit,omitempty"`
+}
+
+type BanAudit struct {
+	Action string    `json:"action"`
+	Actor  string    `json:"actor"`
+	Detail string    `json:"detail,omitempty"`
+	At     time.Time `json:"at"`
+}
+
+type ModerationReq struct {
+	// Player is an xuid or a gamertag.
+	Player string `json:"player"`
+	Reason string `json:"reason"`
+	// Duration ends a mute on its own, e.g. "30m" or "1d". Without it the
+	// mute lasts until an unmute.
+	Duration string `json:"duration"`
+
+	ActorId uint   `json:"-"`
+	Actor   string `json:"-"`
+}
+
+type ModerationAction struct {
+	ID        uint       `json:"id"`
+	Action    string     `json:"action"`
+	Xuid      string     `json:"xuid"`
+	Gamertag  string     `json:"gamertag"`
+	World     string     `json:"world"`
+	Reason    string     `json:"reason"`
+	Actor     string     `json:"actor"`
+	CreatedAt time.Time  `json:"created_at"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	LiftedAt  *time.Time `json:"lifted_at,omitempty"`
+	// Active and Pending only apply to mutes, Pending means the server gets
+	// the mute or unmute when the player joins next.
+	Active  bool `json:"active,omitempty"`
+	Pending bool `json:"pending,omitempty"`
+}
+
+type ModerationHistory struct {
+	Xuid     string             `json:"xuid"`
+	Gamertag string             `json:"gamertag"`
+	Warnings int                `json:"warnings"`
+	Mutes    int                `json:"mutes"`
+	Kicks    int                `json:"kicks"`
+	Muted    []string           `json:"muted_on"`
+	Actions  []ModerationAction `json:"actions"`
+	Bans     []Ban              `json:"bans"`
+}
+
+// PropertySpec describes one server.properties key. Enum lists the accepted
+// words, for int and float keys they are accepted next to numbers.
+type PropertySpec struct {
+	Key     string   `json:"key"`
+	Type    string   `json:"type"`
+	Default string   `json:"default"`
+	Enum    []string `json:"enum,omitempty"`
+	Min     *float64 `json:"min,omitempty"`
+	Max     *float64 `json:"max,omitempty"`
+	// Managed keys follow the world settings and cannot be patched.
+	Managed bool `json:"managed,omitempty"`
+}
+
+type ServerProperties struct {
+	World string `json:"world"`
+	// Properties holds the keys in the file with typed values, keys outside
+	// the schema stay strings.
+	Properties map[string]interface{} `json:"properties"`
+	ApplyResult
+}
+
+// ApplyResult tells how an edit reached a running world.
+type ApplyResult struct {
+	Applied        []string `json:"applied,omitempty"`
+	PendingRestart []string `json:"pending_restart,omitempty"`
+	Restarted      bool     `json:"restarted,omitempty"`
 }
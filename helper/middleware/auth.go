@@ -12,8 +12,22 @@ type key int
 var AuthKey key = 0
 
 func AuthMiddeware(next http.Handler) http.Handler {
+	return authenticate(next, false)
+}
+
+// StreamAuthMiddeware also takes the token from the token query parameter,
+// browsers cannot set headers on WebSocket and EventSource requests. It is
+// only meant for those routes, a token in any other url ends up in logs.
+func StreamAuthMiddeware(next http.Handler) http.Handler {
+	return authenticate(next, true)
+}
+
+func authenticate(next http.Handler, queryToken bool) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
+		if authHeader == "" && queryToken && r.URL.Query().Get("token") != "" {
+			authHeader = "Bearer " + r.URL.Query().Get("token")
+		}
 		if authHeader == "" {
 			http.Error(w, "Unauthorized: No token provided", http.StatusUnauthorized)
 			return
@@ -0,0 +1,19 @@
+package middleware
+
+import (
+	"net/http"
+	"sync/atomic"
+)
+
+// Drain answers 503 once draining is set, so no new world action starts
+// while the manager is shutting its servers down.
+func Drain(next http.Handler, draining *atomic.Bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if draining.Load() {
+			w.Header().Set("Connection", "close")
+			http.Error(w, "Service Unavailable: server is shutting down", http.StatusServiceUnavailable)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
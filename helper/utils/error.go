@@ -8,3 +8,26 @@ var (
 	ErrInvalidEmail = errors.New("invalid email")
 	ErrUnauhorized  = errors.New("you unauthorized for this action")
 )
+
+var (
+	ErrInvalidState       = errors.New("invalid server state")
+	ErrPortInUse          = errors.New("port already in use")
+	ErrStartTimeout       = errors.New("server did not become ready in time")
+	ErrInvalidCommand     = errors.New("invalid command")
+	ErrInvalidSched       = errors.New("invalid schedule")
+	ErrLogNotFound        = errors.New("log file not found")
+	ErrInvalidQuery       = errors.New("invalid query")
+	ErrInvalidProp        = errors.New("invalid server property")
+	ErrInvalidRule        = errors.New("invalid gamerule")
+	ErrPreconditionFailed = errors.New("precondition failed")
+
+	ErrPlayerNotFound   = errors.New("player not found")
+	ErrAmbiguousPlayer  = errors.New("gamertag matches more than one player")
+	ErrPlayerBanned     = errors.New("player is banned")
+	ErrBanNotFound      = errors.New("ban not found")
+	ErrInvalidBan       = errors.New("invalid ban")
+	ErrPlayerOffline    = errors.New("player is not online")
+	ErrInvalidAction    = errors.New("invalid moderation action")
+	ErrCommandRejected  = errors.New("server rejected the command")
+	ErrInvalidAllowlist = errors.New("invalid allowlist entry")
+)
@@ -4,8 +4,13 @@ import (
 	"fmt"
 	"minecrat_go/dto"
 	"regexp"
+	"strings"
 )
 
+// reservedWorldNames are fixed paths under /bedrock, the routes of a world
+// with one of these names would be shadowed by them.
+var reservedWorldNames = []string{"players", "bans", "properties", "events", "create", "start", "get-worlds"}
+
 func IsValidEmail(email string) bool {
 	regex := `^[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}$`
 	re := regexp.MustCompile(regex)
@@ -13,15 +18,35 @@ func IsValidEmail(email string) bool {
 }
 
 func ValidateReq(req *dto.ServerParams) error {
+	for _, name := range reservedWorldNames {
+		if strings.EqualFold(req.Name, name) {
+			return fmt.Errorf("nama world %s dipakai oleh API", req.Name)
+		}
+	}
 	if req.GameMode != "survival" && req.GameMode != "creative" && req.GameMode != "adventure" {
 		return fmt.Errorf("gamemode salah")
 	}
-	if req.Difficult != "easy" && req.Difficult != "normal" && req.Difficult != "hard" {
+	if req.Difficult != "peaceful" && req.Difficult != "easy" && req.Difficult != "normal" && req.Difficult != "hard" {
 		return fmt.Errorf("difficult salah")
 	}
 
 	if req.DefaultPermissionPlayer != "visitor" && req.DefaultPermissionPlayer != "member" && req.DefaultPermissionPlayer != "operator" {
 		return fmt.Errorf("gamemode permission")
 	}
+	if req.Port < 0 || req.Port > 65535 || req.PortV6 < 0 || req.PortV6 > 65535 || (req.Port != 0 && req.Port == req.PortV6) {
+		return fmt.Errorf("port salah")
+	}
+	if req.RestartPolicy != "" && req.RestartPolicy != "never" && req.RestartPolicy != "on-failure" && req.RestartPolicy != "always" {
+		return fmt.Errorf("restart policy salah")
+	}
+	if req.RestartMaxRetries < 0 {
+		return fmt.Errorf("restart max retries salah")
+	}
+	if req.Runtime != "" && req.Runtime != "process" && req.Runtime != "namespace" && req.Runtime != "oci" {
+		return fmt.Errorf("runtime salah")
+	}
+	if req.CPUQuota < -1 || req.MemoryMaxMB < -1 || req.PidsMax < -1 || req.IdleShutdownMinutes < -1 {
+		return fmt.Errorf("resource limit salah")
+	}
 	return nil
 }
@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"errors"
+	"minecrat_go/helper/utils"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *BedrockHandler) GetAccessDrift(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	response, err := h.bduc.GetAccessDrift(params["world"])
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// ReconcileAccess rewrites the files from the database, ?source=file takes
+// the hand edits into the database instead.
+func (h *BedrockHandler) ReconcileAccess(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	response, err := h.bduc.ReconcileAccess(params["world"], r.URL.Query().Get("source"))
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidQuery) {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
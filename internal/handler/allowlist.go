@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"net/http"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// maxAllowlistUpload bounds the body of a bulk replace.
+const maxAllowlistUpload = 4 << 20
+
+func allowlistErrorStatus(err error) int {
+	if errors.Is(err, utils.ErrInvalidAllowlist) {
+		return http.StatusBadRequest
+	}
+	return fileErrorStatus(err)
+}
+
+// parseAllowlistCSV reads one player per row, a xuid or gamertag followed by
+// an optional ignoresPlayerLimit. A header row is skipped.
+func parseAllowlistCSV(r io.Reader) ([]dto.Allowlist, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	var entries []dto.Allowlist
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			return entries, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		player := strings.TrimSpace(record[0])
+		if line == 1 {
+			switch strings.ToLower(player) {
+			case "player", "xuid", "name", "gamertag":
+				continue
+			}
+		}
+		if player == "" {
+			continue
+		}
+
+		// the usecase tells a xuid from a gamertag
+		entry := dto.Allowlist{Xuid: player}
+		if len(record) > 1 && strings.TrimSpace(record[1]) != "" {
+			entry.Priority, err = strconv.ParseBool(strings.TrimSpace(record[1]))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: ignoresPlayerLimit must be true or false", line)
+			}
+		}
+		entries = append(entries, entry)
+	}
+}
+
+// readAllowlistUpload takes a json array of allowlist entries or a csv file,
+// either as the body or as the file field of a multipart form.
+func readAllowlistUpload(r *http.Request) ([]dto.Allowlist, error) {
+	body := io.Reader(r.Body)
+	format, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+
+	if format == "multipart/form-data" {
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		body = file
+		format, _, _ = mime.ParseMediaType(header.Header.Get("Content-Type"))
+		if strings.EqualFold(filepath.Ext(header.Filename), ".csv") {
+			format = "text/csv"
+		}
+	}
+
+	if format == "text/csv" {
+		return parseAllowlistCSV(body)
+	}
+	var entries []dto.Allowlist
+	if err := json.NewDecoder(body).Decode(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func (h *BedrockHandler) GetAllowlist(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	response, etag, err := h.bduc.GetAllowlist(params["world"])
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BedrockHandler) setAllowlistEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	params := mux.Vars(r)
+
+	response, err := h.bduc.SetAllowlistEnabled(params["world"], enabled)
+	if err != nil {
+		if errors.Is(err, utils.ErrCommandRejected) {
+			utils.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BedrockHandler) EnableAllowlist(w http.ResponseWriter, r *http.Request) {
+	h.setAllowlistEnabled(w, r, true)
+}
+
+func (h *BedrockHandler) DisableAllowlist(w http.ResponseWriter, r *http.Request) {
+	h.setAllowlistEnabled(w, r, false)
+}
+
+func (h *BedrockHandler) AddToAllowlist(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	var req dto.Allowlist
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	etag, err := h.bduc.CreatePriority(&req, params["world"], r.Header.Get("If-Match"))
+	if err != nil {
+		utils.WriteError(w, allowlistErrorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	utils.WriteJSON(w, http.StatusOK, req)
+}
+
+func (h *BedrockHandler) RemoveFromAllowlist(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	etag, err := h.bduc.RemoveFromAllowlist(params["world"], params["player"], r.Header.Get("If-Match"))
+	if err != nil {
+		utils.WriteError(w, allowlistErrorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	utils.WriteJSON(w, http.StatusOK, nil)
+}
+
+// ReplaceAllowlist takes the whole allowlist as a json array of entries or a
+// csv upload, see readAllowlistUpload.
+func (h *BedrockHandler) ReplaceAllowlist(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	r.Body = http.MaxBytesReader(w, r.Body, maxAllowlistUpload)
+
+	entries, err := readAllowlistUpload(r)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, etag, err := h.bduc.ReplaceAllowlist(params["world"], entries, r.Header.Get("If-Match"))
+	if err != nil {
+		utils.WriteError(w, allowlistErrorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BedrockHandler) CopyAllowlist(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	var req dto.AllowlistCopyReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, etag, err := h.bduc.CopyAllowlist(params["world"], req.From, r.Header.Get("If-Match"))
+	if err != nil {
+		utils.WriteError(w, allowlistErrorStatus(err), err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	utils.WriteJSON(w, http.StatusOK, response)
+}
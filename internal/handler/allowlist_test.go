@@ -0,0 +1,81 @@
+package handler
+
+import (
+	"minecrat_go/dto"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseAllowlistCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		csv     string
+		want    []dto.Allowlist
+		wantErr bool
+	}{
+		{
+			name: "header is skipped",
+			csv:  "player,ignoresPlayerLimit\nSteve,true\n2535400000000002,false\n",
+			want: []dto.Allowlist{{Xuid: "Steve", Priority: true}, {Xuid: "2535400000000002"}},
+		},
+		{
+			name: "other header names",
+			csv:  "Gamertag\nSteve\n",
+			want: []dto.Allowlist{{Xuid: "Steve"}},
+		},
+		{
+			name: "a header name after the first row is a player",
+			csv:  "Steve\nname\n",
+			want: []dto.Allowlist{{Xuid: "Steve"}, {Xuid: "name"}},
+		},
+		{
+			name: "duplicates are left to the usecase",
+			csv:  "Steve\nSteve,true\n",
+			want: []dto.Allowlist{{Xuid: "Steve"}, {Xuid: "Steve", Priority: true}},
+		},
+		{
+			name: "spaces, blank rows and a missing limit",
+			csv:  "  Steve Jobs , true\n,\n\nAlex,\n",
+			want: []dto.Allowlist{{Xuid: "Steve Jobs", Priority: true}, {Xuid: "Alex"}},
+		},
+		{
+			name: "crlf",
+			csv:  "xuid\r\n2535400000000001\r\n",
+			want: []dto.Allowlist{{Xuid: "2535400000000001"}},
+		},
+		{
+			name: "empty file",
+			csv:  "",
+			want: nil,
+		},
+		{
+			name:    "bad limit",
+			csv:     "Steve,sometimes\n",
+			wantErr: true,
+		},
+		{
+			name:    "bad quoting",
+			csv:     "\"Steve\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseAllowlistCSV(strings.NewReader(tt.csv))
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("got %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
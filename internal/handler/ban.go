@@ -0,0 +1,161 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"minecrat_go/dto"
+	"minecrat_go/helper/middleware"
+	"minecrat_go/helper/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func banErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, utils.ErrInvalidBan):
+		return http.StatusBadRequest
+	case errors.Is(err, utils.ErrBanNotFound):
+		return http.StatusNotFound
+	default:
+		return playerErrorStatus(err)
+	}
+}
+
+// decodeOptional reads an optional json body, an empty body is a zero request.
+func decodeOptional(r *http.Request, v interface{}) error {
+	if err := json.NewDecoder(r.Body).Decode(v); err != nil && !errors.Is(err, io.EOF) {
+		return err
+	}
+	return nil
+}
+
+func (h *BedrockHandler) createBan(w http.ResponseWriter, r *http.Request, req *dto.BanReq) {
+	claims, ok := r.Context().Value(middleware.AuthKey).(*utils.JWTClaims)
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, "invalid jwt")
+		return
+	}
+	req.ActorId = claims.UserID
+	req.Actor = claims.Email
+
+	response, err := h.bduc.CreateBan(req)
+	if err != nil {
+		utils.WriteError(w, banErrorStatus(err), err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// CreateBan bans a player from one world or, without world, from all of them.
+func (h *BedrockHandler) CreateBan(w http.ResponseWriter, r *http.Request) {
+	var req dto.BanReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.createBan(w, r, &req)
+}
+
+// TempBan is CreateBan with a mandatory duration.
+func (h *BedrockHandler) TempBan(w http.ResponseWriter, r *http.Request) {
+	var req dto.BanReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if req.Duration == "" {
+		utils.WriteError(w, http.StatusBadRequest, "duration is required for a temporary ban")
+		return
+	}
+	h.createBan(w, r, &req)
+}
+
+// BanPlayer bans a player from one world, the body with reason and duration
+// is optional.
+func (h *BedrockHandler) BanPlayer(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	var req dto.BanReq
+	if err := decodeOptional(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Player = params["name"]
+	req.World = params["world"]
+	h.createBan(w, r, &req)
+}
+
+// Unban lifts a ban, the body with a reason is optional.
+func (h *BedrockHandler) Unban(w http.ResponseWriter, r *http.Request) {
+	claims, ok := r.Context().Value(middleware.AuthKey).(*utils.JWTClaims)
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, "invalid jwt")
+		return
+	}
+
+	params := mux.Vars(r)
+	paramsId, err := strconv.ParseUint(params["id"], 10, 64)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	var req dto.UnbanReq
+	if err := decodeOptional(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.ActorId = claims.UserID
+	req.Actor = claims.Email
+
+	response, err := h.bduc.LiftBan(uint(paramsId), &req)
+	if err != nil {
+		utils.WriteError(w, banErrorStatus(err), err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// GetBans lists bans, filtered by ?player=, ?world= and ?active=true.
+func (h *BedrockHandler) GetBans(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+
+	var active bool
+	if raw := query.Get("active"); raw != "" {
+		var err error
+		if active, err = strconv.ParseBool(raw); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid active")
+			return
+		}
+	}
+
+	response, err := h.bduc.GetBans(query.Get("player"), query.Get("world"), active)
+	if err != nil {
+		utils.WriteError(w, banErrorStatus(err), err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BedrockHandler) GetBan(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	paramsId, err := strconv.ParseUint(params["id"], 10, 64)
+	if err != nil {
+		utils.WriteError(w, http.StatusBadRequest, "invalid id")
+		return
+	}
+
+	response, err := h.bduc.GetBan(uint(paramsId))
+	if err != nil {
+		utils.WriteError(w, banErrorStatus(err), err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
@@ -2,12 +2,15 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"minecrat_go/dto"
 	"minecrat_go/helper/middleware"
 	"minecrat_go/helper/utils"
 	"minecrat_go/internal/usecase"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 )
@@ -41,6 +44,10 @@ func (h *BedrockHandler) CreateWorld(w http.ResponseWriter, r *http.Request) {
 
 	req.Creator = claims.UserID
 	if err := h.bduc.CreateServer(&req); err != nil {
+		if errors.Is(err, utils.ErrPortInUse) {
+			utils.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
 		utils.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
@@ -74,29 +81,48 @@ func (h *BedrockHandler) StartWorld(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.bduc.StartServer(&req); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+	req.Wait = r.URL.Query().Get("wait") != "false"
+
+	response, err := h.bduc.StartServer(&req)
+	if err != nil {
+		switch {
+		case errors.Is(err, utils.ErrInvalidState), errors.Is(err, utils.ErrPortInUse):
+			utils.WriteError(w, http.StatusConflict, err.Error())
+		case errors.Is(err, utils.ErrStartTimeout):
+			utils.WriteError(w, http.StatusGatewayTimeout, err.Error())
+		default:
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		}
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, nil)
+	if !req.Wait {
+		utils.WriteJSON(w, http.StatusAccepted, response)
+		return
+	}
+	utils.WriteJSON(w, http.StatusOK, response)
 }
 
 func (h *BedrockHandler) StopWorld(w http.ResponseWriter, r *http.Request) {
-	var req dto.StartServerReq
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+	var req dto.StopServerReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
 		utils.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
 	params := mux.Vars(r)
 	paramsWorld := params["world"]
 
-	if err := h.bduc.StopServer(paramsWorld); err != nil {
+	response, err := h.bduc.StopServer(paramsWorld, time.Duration(req.Timeout)*time.Second)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidState) {
+			utils.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
 		utils.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, nil)
+	utils.WriteJSON(w, http.StatusOK, response)
 }
 
 func (h *BedrockHandler) EditWorld(w http.ResponseWriter, r *http.Request) {
@@ -126,18 +152,26 @@ func (h *BedrockHandler) EditWorld(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	req.Creator = claims.UserID
-	if err := h.bduc.EditWorld(&req, uint(paramsId), paramsWorld); err != nil {
+	req.Restart, _ = strconv.ParseBool(r.URL.Query().Get("restart"))
+	response, err := h.bduc.EditWorld(&req, uint(paramsId), paramsWorld)
+	if err != nil {
+		if errors.Is(err, utils.ErrPortInUse) || errors.Is(err, utils.ErrInvalidState) || errors.Is(err, utils.ErrCommandRejected) {
+			utils.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		if errors.Is(err, utils.ErrInvalidRule) {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		utils.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, nil)
+	utils.WriteJSON(w, http.StatusOK, response)
 }
 
 func (h *BedrockHandler) SendCommand(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		CMD string `json:"cmd"`
-	}
+	var req dto.CommandReq
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		utils.WriteError(w, http.StatusBadRequest, err.Error())
 		return
@@ -146,50 +180,61 @@ func (h *BedrockHandler) SendCommand(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	paramsWorld := params["world"]
 
-	if err := h.bduc.SendCommandforAPI(paramsWorld, req.CMD); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+	// ?wait=false keeps the old fire-and-forget behaviour
+	if r.URL.Query().Get("wait") == "false" {
+		if err := h.bduc.SendCommandforAPI(paramsWorld, req.CMD); err != nil {
+			if errors.Is(err, utils.ErrInvalidCommand) {
+				utils.WriteError(w, http.StatusBadRequest, err.Error())
+				return
+			}
+			if errors.Is(err, utils.ErrInvalidState) {
+				utils.WriteError(w, http.StatusConflict, err.Error())
+				return
+			}
+			utils.WriteError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		utils.WriteJSON(w, http.StatusAccepted, nil)
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, nil)
-}
-
-func (h *BedrockHandler) BanPlayer(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	paramsName := params["name"]
-	paramsWorld := params["world"]
-
-	if err := h.bduc.BanPlayer(paramsWorld, paramsName); err != nil {
+	response, err := h.bduc.RunCommand(paramsWorld, req.CMD, time.Duration(req.Timeout)*time.Second)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidCommand) {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, utils.ErrInvalidState) {
+			utils.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
 		utils.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
-	utils.WriteJSON(w, http.StatusOK, nil)
+	utils.WriteJSON(w, http.StatusOK, response)
 }
 
-func (h *BedrockHandler) KickPlayer(w http.ResponseWriter, r *http.Request) {
-	params := mux.Vars(r)
-	paramsName := params["name"]
-	paramsWorld := params["world"]
-
-	if err := h.bduc.KickPlayer(paramsWorld, paramsName); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err.Error())
-		return
+// fileErrorStatus maps the errors of edits to permissions.json and
+// allowlist.json, a stale If-Match is 412.
+func fileErrorStatus(err error) int {
+	if errors.Is(err, utils.ErrPreconditionFailed) {
+		return http.StatusPreconditionFailed
 	}
-
-	utils.WriteJSON(w, http.StatusOK, nil)
+	return playerErrorStatus(err)
 }
 
 func (h *BedrockHandler) GetPermissionPlayer(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	paramsWorld := params["world"]
 
-	response, err := h.bduc.GetPermissionPlayer(paramsWorld)
+	response, etag, err := h.bduc.GetPermissionPlayer(paramsWorld)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 
+	w.Header().Set("ETag", etag)
 	utils.WriteJSON(w, http.StatusOK, response)
 }
 
@@ -225,11 +270,13 @@ func (h *BedrockHandler) CreateOrUpdatePermissionPlayer(w http.ResponseWriter, r
 		utils.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := h.bduc.CreateOrUpdatePermissions(&req, paramsWorld); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+	etag, err := h.bduc.CreateOrUpdatePermissions(&req, paramsWorld, r.Header.Get("If-Match"))
+	if err != nil {
+		utils.WriteError(w, fileErrorStatus(err), err.Error())
 		return
 	}
 
+	w.Header().Set("ETag", etag)
 	utils.WriteJSON(w, http.StatusOK, nil)
 }
 
@@ -238,11 +285,13 @@ func (h *BedrockHandler) DeletePermissionPlayer(w http.ResponseWriter, r *http.R
 	paramsWorld := params["world"]
 	paramsUid := params["xuid"]
 
-	if err := h.bduc.DeletePermission(paramsUid, paramsWorld); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+	etag, err := h.bduc.DeletePermission(paramsUid, paramsWorld, r.Header.Get("If-Match"))
+	if err != nil {
+		utils.WriteError(w, fileErrorStatus(err), err.Error())
 		return
 	}
 
+	w.Header().Set("ETag", etag)
 	utils.WriteJSON(w, http.StatusOK, nil)
 }
 
@@ -268,11 +317,13 @@ func (h *BedrockHandler) CreatePriority(w http.ResponseWriter, r *http.Request)
 		utils.WriteError(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := h.bduc.CreatePriority(&req, paramsWorld); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+	etag, err := h.bduc.CreatePriority(&req, paramsWorld, r.Header.Get("If-Match"))
+	if err != nil {
+		utils.WriteError(w, allowlistErrorStatus(err), err.Error())
 		return
 	}
 
+	w.Header().Set("ETag", etag)
 	utils.WriteJSON(w, http.StatusOK, nil)
 }
 
@@ -281,11 +332,13 @@ func (h *BedrockHandler) DeletePriority(w http.ResponseWriter, r *http.Request)
 	paramsWorld := params["world"]
 	paramsXuid := params["xuid"]
 
-	if err := h.bduc.DeletePriority(paramsXuid, paramsWorld); err != nil {
-		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+	etag, err := h.bduc.DeletePriority(paramsXuid, paramsWorld, r.Header.Get("If-Match"))
+	if err != nil {
+		utils.WriteError(w, fileErrorStatus(err), err.Error())
 		return
 	}
 
+	w.Header().Set("ETag", etag)
 	utils.WriteJSON(w, http.StatusOK, nil)
 }
 
@@ -293,7 +346,34 @@ func (h *BedrockHandler) GetPriority(w http.ResponseWriter, r *http.Request) {
 	params := mux.Vars(r)
 	paramsWorld := params["world"]
 
-	response, err := h.bduc.GetPriority(paramsWorld)
+	response, etag, err := h.bduc.GetPriority(paramsWorld)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("ETag", etag)
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BedrockHandler) GetServerStatus(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	paramsWorld := params["world"]
+
+	response, err := h.bduc.GetServerStatus(paramsWorld)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BedrockHandler) GetResourceUsage(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	paramsWorld := params["world"]
+
+	response, err := h.bduc.GetResourceUsage(paramsWorld)
 	if err != nil {
 		utils.WriteError(w, http.StatusInternalServerError, err.Error())
 		return
@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"minecrat_go/helper/utils"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+)
+
+const (
+	consoleWriteWait  = 10 * time.Second
+	consolePongWait   = 60 * time.Second
+	consolePingPeriod = consolePongWait * 9 / 10
+	consoleMaxHistory = 1000
+)
+
+var consoleUpgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 4096,
+}
+
+// Console streams the output of a world over a WebSocket. Every text frame
+// sent by the server is one console line, every text frame received is run
+// as a command. Messages from the manager itself start with "[manager]".
+func (h *BedrockHandler) Console(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	paramsWorld := params["world"]
+
+	history := 100
+	if raw := r.URL.Query().Get("history"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			utils.WriteError(w, http.StatusBadRequest, "invalid history")
+			return
+		}
+		history = min(n, consoleMaxHistory)
+	}
+
+	lines, cancel, err := h.bduc.SubscribeConsole(paramsWorld, history)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	defer cancel()
+
+	conn, err := consoleUpgrader.Upgrade(w, r, nil)
+	if err != nil {
+		// Upgrade already answered the client
+		return
+	}
+	defer conn.Close()
+
+	notices := make(chan string, 16)
+	done := make(chan struct{})
+	go consoleReader(conn, notices, done, func(command string) error {
+		return h.bduc.SendCommandforAPI(paramsWorld, command)
+	})
+
+	ping := time.NewTicker(consolePingPeriod)
+	defer ping.Stop()
+
+	// this goroutine is the only writer of conn
+	for {
+		var err error
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				conn.SetWriteDeadline(time.Now().Add(consoleWriteWait))
+				conn.WriteMessage(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "console stream closed"))
+				return
+			}
+			conn.SetWriteDeadline(time.Now().Add(consoleWriteWait))
+			err = conn.WriteMessage(websocket.TextMessage, []byte(line))
+		case notice := <-notices:
+			conn.SetWriteDeadline(time.Now().Add(consoleWriteWait))
+			err = conn.WriteMessage(websocket.TextMessage, []byte("[manager] "+notice))
+		case <-ping.C:
+			err = conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(consoleWriteWait))
+		case <-done:
+			return
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// consoleReader runs the commands typed into the socket until it closes.
+func consoleReader(conn *websocket.Conn, notices chan<- string, done chan<- struct{}, send func(string) error) {
+	defer close(done)
+
+	conn.SetReadLimit(4096)
+	conn.SetReadDeadline(time.Now().Add(consolePongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(consolePongWait))
+	})
+
+	for {
+		_, msg, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		command := strings.TrimSpace(string(msg))
+		if command == "" {
+			continue
+		}
+		if err := send(command); err != nil {
+			select {
+			case notices <- "command failed: " + err.Error():
+			default:
+			}
+		}
+	}
+}
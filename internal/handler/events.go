@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"minecrat_go/helper/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const eventsKeepAlive = 15 * time.Second
+
+// WorldEvents is the text/event-stream of one world.
+func (h *BedrockHandler) WorldEvents(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	h.streamEvents(w, r, params["world"])
+}
+
+// Events is the text/event-stream of every world.
+func (h *BedrockHandler) Events(w http.ResponseWriter, r *http.Request) {
+	h.streamEvents(w, r, "")
+}
+
+func (h *BedrockHandler) streamEvents(w http.ResponseWriter, r *http.Request, world string) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		utils.WriteError(w, http.StatusInternalServerError, "streaming unsupported")
+		return
+	}
+
+	// EventSource sends Last-Event-ID on reconnect, scripts can use the query
+	lastRaw := r.Header.Get("Last-Event-ID")
+	if lastRaw == "" {
+		lastRaw = r.URL.Query().Get("last_event_id")
+	}
+	var lastID uint64
+	if lastRaw != "" {
+		id, err := strconv.ParseUint(lastRaw, 10, 64)
+		if err != nil {
+			utils.WriteError(w, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+		lastID = id
+	}
+
+	events, cancel, err := h.bduc.SubscribeEvents(world, lastID)
+	if err != nil {
+		utils.WriteError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	defer cancel()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 3000\n\n")
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				// dropped as a slow consumer or shutting down, the client
+				// reconnects with Last-Event-ID
+				return
+			}
+			data, err := json.Marshal(event)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+			flusher.Flush()
+		case <-keepAlive.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
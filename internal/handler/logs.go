@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+// GetLogHistory searches the log files of a world. from and to take RFC 3339
+// times, q is a case-insensitive substring, regex a Go regular expression.
+func (h *BedrockHandler) GetLogHistory(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	paramsWorld := params["world"]
+
+	query := r.URL.Query()
+	req := dto.LogQuery{
+		Query: query.Get("q"),
+		Regex: query.Get("regex"),
+	}
+
+	var err error
+	for key, dst := range map[string]*time.Time{"from": &req.From, "to": &req.To} {
+		raw := query.Get(key)
+		if raw == "" {
+			continue
+		}
+		if *dst, err = time.Parse(time.RFC3339, raw); err != nil {
+			utils.WriteError(w, http.StatusBadRequest, fmt.Sprintf("invalid %s, use RFC 3339", key))
+			return
+		}
+	}
+	for key, dst := range map[string]*int{"offset": &req.Offset, "limit": &req.Limit} {
+		raw := query.Get(key)
+		if raw == "" {
+			continue
+		}
+		if *dst, err = strconv.Atoi(raw); err != nil || *dst < 0 {
+			utils.WriteError(w, http.StatusBadRequest, "invalid "+key)
+			return
+		}
+	}
+
+	response, err := h.bduc.GetLogHistory(paramsWorld, req)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidQuery) {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BedrockHandler) GetLogFiles(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	paramsWorld := params["world"]
+
+	response, err := h.bduc.GetLogFiles(paramsWorld)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// DownloadLogFile sends one log file as an attachment, rotated files stay
+// gzipped.
+func (h *BedrockHandler) DownloadLogFile(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	paramsWorld := params["world"]
+	paramsFile := params["file"]
+
+	path, err := h.bduc.LogFilePath(paramsWorld, paramsFile)
+	if err != nil {
+		if errors.Is(err, utils.ErrLogNotFound) {
+			utils.WriteError(w, http.StatusNotFound, err.Error())
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", paramsWorld+"-"+paramsFile))
+	http.ServeFile(w, r, path)
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"minecrat_go/dto"
+	"minecrat_go/helper/middleware"
+	"minecrat_go/helper/utils"
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+func moderationErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, utils.ErrInvalidAction):
+		return http.StatusBadRequest
+	case errors.Is(err, utils.ErrPlayerOffline), errors.Is(err, utils.ErrCommandRejected):
+		return http.StatusConflict
+	default:
+		return playerErrorStatus(err)
+	}
+}
+
+type moderationFunc func(name string, req *dto.ModerationReq) (*dto.ModerationAction, error)
+
+func (h *BedrockHandler) moderate(w http.ResponseWriter, r *http.Request, req *dto.ModerationReq, action moderationFunc) {
+	claims, ok := r.Context().Value(middleware.AuthKey).(*utils.JWTClaims)
+	if !ok {
+		utils.WriteError(w, http.StatusUnauthorized, "invalid jwt")
+		return
+	}
+	req.ActorId = claims.UserID
+	req.Actor = claims.Email
+
+	response, err := action(mux.Vars(r)["world"], req)
+	if err != nil {
+		utils.WriteError(w, moderationErrorStatus(err), err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BedrockHandler) moderateBody(w http.ResponseWriter, r *http.Request, action moderationFunc) {
+	var req dto.ModerationReq
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.moderate(w, r, &req, action)
+}
+
+func (h *BedrockHandler) WarnPlayer(w http.ResponseWriter, r *http.Request) {
+	h.moderateBody(w, r, h.bduc.WarnPlayer)
+}
+
+// MutePlayer mutes a player, with a duration the mute ends on its own.
+func (h *BedrockHandler) MutePlayer(w http.ResponseWriter, r *http.Request) {
+	h.moderateBody(w, r, h.bduc.MutePlayer)
+}
+
+func (h *BedrockHandler) UnmutePlayer(w http.ResponseWriter, r *http.Request) {
+	h.moderateBody(w, r, h.bduc.UnmutePlayer)
+}
+
+func (h *BedrockHandler) ModerationKick(w http.ResponseWriter, r *http.Request) {
+	h.moderateBody(w, r, h.bduc.KickPlayer)
+}
+
+// KickPlayer kicks the player in the path, the body with a reason is
+// optional.
+func (h *BedrockHandler) KickPlayer(w http.ResponseWriter, r *http.Request) {
+	var req dto.ModerationReq
+	if err := decodeOptional(r, &req); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	req.Player = mux.Vars(r)["name"]
+	h.moderate(w, r, &req, h.bduc.KickPlayer)
+}
+
+// GetModerationHistory lists the warnings, mutes, kicks and bans of a player,
+// ?world= narrows it to one world.
+func (h *BedrockHandler) GetModerationHistory(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	response, err := h.bduc.GetModerationHistory(params["player"], r.URL.Query().Get("world"))
+	if err != nil {
+		utils.WriteError(w, moderationErrorStatus(err), err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"errors"
+	"minecrat_go/helper/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+// playerErrorStatus maps the errors of endpoints that take an xuid or a
+// gamertag.
+func playerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, utils.ErrPlayerNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, utils.ErrAmbiguousPlayer), errors.Is(err, utils.ErrPlayerBanned), errors.Is(err, utils.ErrInvalidState):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+func (h *BedrockHandler) GetPlayers(w http.ResponseWriter, r *http.Request) {
+	limit, offset, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := h.bduc.GetPlayers(r.URL.Query().Get("q"), limit, offset)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// GetPlayer takes an xuid or a gamertag, past gamertags included.
+func (h *BedrockHandler) GetPlayer(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	paramsPlayer := params["player"]
+
+	response, err := h.bduc.GetPlayer(paramsPlayer)
+	if err != nil {
+		utils.WriteError(w, playerErrorStatus(err), err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// pageParams reads ?limit= and ?offset=, it answers the request itself when
+// they are invalid.
+func pageParams(w http.ResponseWriter, r *http.Request) (int, int, bool) {
+	query := r.URL.Query()
+	var limit, offset int
+	for key, dst := range map[string]*int{"limit": &limit, "offset": &offset} {
+		raw := query.Get(key)
+		if raw == "" {
+			continue
+		}
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			utils.WriteError(w, http.StatusBadRequest, "invalid "+key)
+			return 0, 0, false
+		}
+		*dst = n
+	}
+	return limit, offset, true
+}
+
+func (h *BedrockHandler) GetOnlinePlayers(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	paramsWorld := params["world"]
+
+	response, err := h.bduc.GetOnlinePlayers(paramsWorld)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BedrockHandler) GetPlayerSessions(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	paramsWorld := params["world"]
+	paramsXuid := params["xuid"]
+
+	limit, offset, ok := pageParams(w, r)
+	if !ok {
+		return
+	}
+
+	response, err := h.bduc.GetPlayerSessions(paramsWorld, paramsXuid, limit, offset)
+	if err != nil {
+		utils.WriteError(w, playerErrorStatus(err), err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *BedrockHandler) GetPlaytime(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	paramsWorld := params["world"]
+
+	response, err := h.bduc.GetPlaytime(paramsWorld)
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"minecrat_go/helper/utils"
+	"net/http"
+	"strconv"
+
+	"github.com/gorilla/mux"
+)
+
+func (h *BedrockHandler) GetPropertySchema(w http.ResponseWriter, r *http.Request) {
+	utils.WriteJSON(w, http.StatusOK, h.bduc.GetPropertySchema())
+}
+
+func (h *BedrockHandler) GetProperties(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+
+	response, err := h.bduc.GetProperties(params["world"])
+	if err != nil {
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
+
+// PatchProperties takes a json object of keys to change, null removes a key.
+// ?restart=true restarts a running world when a change cannot be applied
+// live.
+func (h *BedrockHandler) PatchProperties(w http.ResponseWriter, r *http.Request) {
+	params := mux.Vars(r)
+	restart, _ := strconv.ParseBool(r.URL.Query().Get("restart"))
+
+	var patch map[string]json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&patch); err != nil {
+		utils.WriteError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	response, err := h.bduc.PatchProperties(params["world"], patch, restart)
+	if err != nil {
+		if errors.Is(err, utils.ErrInvalidProp) {
+			utils.WriteError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if errors.Is(err, utils.ErrInvalidState) || errors.Is(err, utils.ErrCommandRejected) {
+			utils.WriteError(w, http.StatusConflict, err.Error())
+			return
+		}
+		utils.WriteError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	utils.WriteJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,163 @@
+package repository
+
+import (
+	"errors"
+	"minecrat_go/model"
+
+	"gorm.io/gorm"
+)
+
+func (r *bedrockRepo) GetPermissions(worldId uint) ([]model.Permission, error) {
+	var permissions []model.Permission
+	if err := r.db.Where("world_server_id = ?", worldId).Order("id").Find(&permissions).Error; err != nil {
+		return nil, err
+	}
+	return permissions, nil
+}
+
+// SetPermission creates or changes the permission of xuid on a world.
+func (r *bedrockRepo) SetPermission(worldId uint, xuid, permission string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing model.Permission
+		err := tx.Where("world_server_id = ? AND xuid = ?", worldId, xuid).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(&model.Permission{WorldServerId: worldId, Xuid: xuid, Permission: permission}).Error
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&existing).Update("permission", permission).Error
+	})
+}
+
+// DeletePermission reports whether xuid had a permission on the world.
+func (r *bedrockRepo) DeletePermission(worldId uint, xuid string) (bool, error) {
+	result := r.db.Where("world_server_id = ? AND xuid = ?", worldId, xuid).Delete(&model.Permission{})
+	return result.RowsAffected > 0, result.Error
+}
+
+// ReplacePermissions makes permissions the whole permission list of a world.
+func (r *bedrockRepo) ReplacePermissions(worldId uint, permissions []model.Permission) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("world_server_id = ?", worldId).Delete(&model.Permission{}).Error; err != nil {
+			return err
+		}
+		for i := range permissions {
+			permissions[i].ID = 0
+			permissions[i].WorldServerId = worldId
+		}
+		if len(permissions) == 0 {
+			return nil
+		}
+		return tx.Create(&permissions).Error
+	})
+}
+
+func (r *bedrockRepo) GetAllowlist(worldId uint) ([]model.AllowlistEntry, error) {
+	var entries []model.AllowlistEntry
+	if err := r.db.Where("world_server_id = ?", worldId).Order("id").Find(&entries).Error; err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func allowlistMatch(db *gorm.DB, worldId uint, xuid, name string) *gorm.DB {
+	if xuid != "" {
+		return db.Where("world_server_id = ? AND xuid = ?", worldId, xuid)
+	}
+	return db.Where("world_server_id = ? AND xuid = '' AND LOWER(name) = LOWER(?)", worldId, name)
+}
+
+// SetAllowlistEntry creates the entry or updates the one with the same xuid,
+// or the same name for an entry without xuid.
+func (r *bedrockRepo) SetAllowlistEntry(entry *model.AllowlistEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var existing model.AllowlistEntry
+		err := allowlistMatch(tx, entry.WorldServerId, entry.Xuid, entry.Name).First(&existing).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(entry).Error
+		}
+		if err != nil {
+			return err
+		}
+		entry.ID = existing.ID
+		return tx.Model(&existing).Updates(map[string]interface{}{
+			"name":                 entry.Name,
+			"ignores_player_limit": entry.IgnoresPlayerLimit,
+		}).Error
+	})
+}
+
+// DeleteAllowlistEntries removes a player from the allowlist of a world by
+// xuid, and the entries without xuid by gamertag, and returns what it
+// removed.
+func (r *bedrockRepo) DeleteAllowlistEntries(worldId uint, xuid, gamertag string) ([]model.AllowlistEntry, error) {
+	var removed []model.AllowlistEntry
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		db := tx.Where("world_server_id = ?", worldId)
+		switch {
+		case xuid != "" && gamertag != "":
+			db = db.Where("xuid = ? OR (xuid = '' AND LOWER(name) = LOWER(?))", xuid, gamertag)
+		case xuid != "":
+			db = db.Where("xuid = ?", xuid)
+		default:
+			db = db.Where("xuid = '' AND LOWER(name) = LOWER(?)", gamertag)
+		}
+		if err := db.Find(&removed).Error; err != nil {
+			return err
+		}
+		if len(removed) == 0 {
+			return nil
+		}
+		ids := make([]uint, len(removed))
+		for i, entry := range removed {
+			ids[i] = entry.ID
+		}
+		return tx.Delete(&model.AllowlistEntry{}, ids).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return removed, nil
+}
+
+// ReplaceAllowlist makes entries the whole allowlist of a world.
+func (r *bedrockRepo) ReplaceAllowlist(worldId uint, entries []model.AllowlistEntry) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("world_server_id = ?", worldId).Delete(&model.AllowlistEntry{}).Error; err != nil {
+			return err
+		}
+		for i := range entries {
+			entries[i].ID = 0
+			entries[i].WorldServerId = worldId
+		}
+		if len(entries) == 0 {
+			return nil
+		}
+		return tx.Create(&entries).Error
+	})
+}
+
+// GetPlayerAccess returns the permissions and allowlist entries of xuid on
+// every world.
+func (r *bedrockRepo) GetPlayerAccess(xuid string) ([]model.Permission, []model.AllowlistEntry, error) {
+	var permissions []model.Permission
+	if err := r.db.Preload("WorldServer").Where("xuid = ?", xuid).Order("world_server_id").Find(&permissions).Error; err != nil {
+		return nil, nil, err
+	}
+	var entries []model.AllowlistEntry
+	if err := r.db.Preload("WorldServer").Where("xuid = ?", xuid).Order("world_server_id").Find(&entries).Error; err != nil {
+		return nil, nil, err
+	}
+	return permissions, entries, nil
+}
+
+// GetUnimportedWorlds returns the worlds whose files were never imported
+// into the permission tables.
+func (r *bedrockRepo) GetUnimportedWorlds() ([]model.WorldServer, error) {
+	var worlds []model.WorldServer
+	if err := r.db.Model(&model.WorldServer{}).Select("id", "name").Where("access_imported = ?", false).Find(&worlds).Error; err != nil {
+		return nil, err
+	}
+	return worlds, nil
+}
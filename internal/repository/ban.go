@@ -0,0 +1,109 @@
+package repository
+
+import (
+	"minecrat_go/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// BanFilter narrows GetBans, zero fields match everything. WorldId also
+// matches the bans that cover every world.
+type BanFilter struct {
+	Xuid       string
+	WorldId    uint
+	ActiveOnly bool
+	At         time.Time
+}
+
+func activeBans(db *gorm.DB, at time.Time) *gorm.DB {
+	return db.Where("lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", at)
+}
+
+func (r *bedrockRepo) CreateBan(ban *model.Ban, audit *model.BanAudit) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(ban).Error; err != nil {
+			return err
+		}
+		audit.BanId = ban.ID
+		return tx.Create(audit).Error
+	})
+}
+
+// LiftBan ends a ban early or records that it expired.
+func (r *bedrockRepo) LiftBan(ban *model.Ban, audit *model.BanAudit) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&model.Ban{}).Where("id = ? AND lifted_at IS NULL", ban.ID).Updates(map[string]interface{}{
+			"lifted_at":   ban.LiftedAt,
+			"lifted_by":   ban.LiftedBy,
+			"lift_reason": ban.LiftReason,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		audit.BanId = ban.ID
+		return tx.Create(audit).Error
+	})
+}
+
+func (r *bedrockRepo) AddBanAudit(audit *model.BanAudit) error {
+	return r.db.Create(audit).Error
+}
+
+func (r *bedrockRepo) GetBan(id uint) (*model.Ban, error) {
+	var ban model.Ban
+	err := r.db.Preload("WorldServer").Preload("Audits", func(db *gorm.DB) *gorm.DB {
+		return db.Order("at, id")
+	}).First(&ban, id).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ban, nil
+}
+
+func (r *bedrockRepo) GetBans(filter BanFilter) ([]model.Ban, error) {
+	db := r.db.Preload("WorldServer")
+	if filter.Xuid != "" {
+		db = db.Where("xuid = ?", filter.Xuid)
+	}
+	if filter.WorldId != 0 {
+		db = db.Where("(world_server_id IS NULL OR world_server_id = ?)", filter.WorldId)
+	}
+	if filter.ActiveOnly {
+		db = activeBans(db, filter.At)
+	}
+
+	var bans []model.Ban
+	if err := db.Order("created_at DESC, id DESC").Find(&bans).Error; err != nil {
+		return nil, err
+	}
+	return bans, nil
+}
+
+// GetActiveBan returns the ban that keeps xuid off a world, world wide bans
+// first.
+func (r *bedrockRepo) GetActiveBan(xuid string, worldId uint, at time.Time) (*model.Ban, error) {
+	var ban model.Ban
+	err := activeBans(r.db, at).
+		Where("xuid = ? AND (world_server_id IS NULL OR world_server_id = ?)", xuid, worldId).
+		Order("world_server_id IS NOT NULL, id").
+		First(&ban).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ban, nil
+}
+
+func (r *bedrockRepo) GetExpiredBans(at time.Time) ([]model.Ban, error) {
+	var bans []model.Ban
+	err := r.db.Preload("WorldServer").
+		Where("lifted_at IS NULL AND expires_at IS NOT NULL AND expires_at <= ?", at).
+		Find(&bans).Error
+	if err != nil {
+		return nil, err
+	}
+	return bans, nil
+}
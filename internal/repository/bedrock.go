@@ -5,6 +5,7 @@ import (
 	"minecrat_go/dto"
 	"minecrat_go/helper/utils"
 	"minecrat_go/model"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -16,6 +17,63 @@ type BedrockRepo interface {
 	GetWorlds() ([]dto.GetWorlds, error)
 	GetWorldAndPlayers(name string) (*dto.GetWorldAndPlayers, error)
 	EnsurePlayerExists(xuid string, worldId uint) error
+	GetWorldByName(name string) (*model.WorldServer, error)
+	UpdateWorldProperties(id uint, updates map[string]interface{}) error
+	GetAutostartWorlds() ([]model.WorldServer, error)
+	GetWorldPorts() ([]model.WorldServer, error)
+
+	//schedule
+	CreateSchedule(schedule *model.WorldSchedule) error
+	UpdateSchedule(schedule *model.WorldSchedule) error
+	DeleteSchedule(worldId, id uint) error
+	GetSchedule(worldId, id uint) (*model.WorldSchedule, error)
+	GetSchedules(worldId uint) ([]model.WorldSchedule, error)
+	GetEnabledSchedules() ([]model.WorldSchedule, error)
+	SetScheduleRun(id uint, at time.Time, runErr string) error
+
+	//session
+	CreateSession(session *model.PlayerSession) error
+	CloseSession(id uint, leftAt time.Time, seconds int64, reason string) error
+	GetOpenSessions() ([]model.PlayerSession, error)
+	GetSessions(worldId uint, xuid string, limit, offset int) ([]model.PlayerSession, int64, error)
+	GetPlaytime(worldId uint) ([]dto.Playtime, error)
+
+	//player directory
+	UpsertPlayer(xuid, gamertag string, at time.Time) error
+	GetPlayer(xuid string) (*model.Player, error)
+	GetPlayersByGamertag(gamertag string) ([]model.Player, error)
+	GetPlayersByPastGamertag(gamertag string) ([]model.Player, error)
+	SearchPlayers(query string, limit, offset int) ([]model.Player, int64, error)
+
+	//ban
+	CreateBan(ban *model.Ban, audit *model.BanAudit) error
+	LiftBan(ban *model.Ban, audit *model.BanAudit) error
+	AddBanAudit(audit *model.BanAudit) error
+	GetBan(id uint) (*model.Ban, error)
+	GetBans(filter BanFilter) ([]model.Ban, error)
+	GetActiveBan(xuid string, worldId uint, at time.Time) (*model.Ban, error)
+	GetExpiredBans(at time.Time) ([]model.Ban, error)
+
+	//moderation
+	CreateModeration(action *model.ModerationAction) error
+	GetActiveMute(xuid string, worldId uint, at time.Time) (*model.ModerationAction, error)
+	LiftMute(id uint, at time.Time, pending bool) error
+	SetModerationPending(id uint, pending bool) error
+	GetPendingMutes(xuid string, worldId uint) ([]model.ModerationAction, error)
+	GetExpiredMutes(at time.Time) ([]model.ModerationAction, error)
+	GetModerationHistory(xuid string, worldId uint) ([]model.ModerationAction, error)
+
+	//permission and allowlist
+	GetPermissions(worldId uint) ([]model.Permission, error)
+	SetPermission(worldId uint, xuid, permission string) error
+	DeletePermission(worldId uint, xuid string) (bool, error)
+	ReplacePermissions(worldId uint, permissions []model.Permission) error
+	GetAllowlist(worldId uint) ([]model.AllowlistEntry, error)
+	SetAllowlistEntry(entry *model.AllowlistEntry) error
+	DeleteAllowlistEntries(worldId uint, xuid, gamertag string) ([]model.AllowlistEntry, error)
+	ReplaceAllowlist(worldId uint, entries []model.AllowlistEntry) error
+	GetPlayerAccess(xuid string) ([]model.Permission, []model.AllowlistEntry, error)
+	GetUnimportedWorlds() ([]model.WorldServer, error)
 }
 
 type bedrockRepo struct {
@@ -31,28 +89,54 @@ func (r *bedrockRepo) CreateWorld(req *dto.ServerParams) (*dto.ServerParams, err
 		CreatorId:               &req.Creator,
 		Name:                    req.Name,
 		Port:                    req.Port,
+		PortV6:                  req.PortV6,
 		GameMode:                req.GameMode,
 		Difficult:               req.Difficult,
-		AllowCheat:              req.AllowCheat,
+		AllowCheat:              req.AllowCheat == nil || *req.AllowCheat,
 		MaxPlayer:               req.MaxPlayer,
 		DefaultPermissionPlayer: req.DefaultPermissionPlayer,
 		SeedWorld:               req.SeedWorld,
 		ViewDistance:            req.ViewDistance,
+		RestartPolicy:           req.RestartPolicy,
+		RestartMaxRetries:       req.RestartMaxRetries,
+		Runtime:                 req.Runtime,
+		CPUQuota:                max(req.CPUQuota, 0),
+		MemoryMaxMB:             max(req.MemoryMaxMB, 0),
+		PidsMax:                 max(req.PidsMax, 0),
+		IdleShutdownMinutes:     max(req.IdleShutdownMinutes, 0),
+		// a new world starts with the files rendered from the database
+		AccessImported: true,
 	}
+	if req.Autostart != nil {
+		newWorld.Autostart = *req.Autostart
+	}
+	newWorld.StartOrder = req.StartOrder
 
 	if err := r.db.Debug().Model(&model.WorldServer{}).Create(&newWorld).Error; err != nil {
 		return nil, err
 	}
+	// gorm writes the column default instead of a false bool
+	if !newWorld.AllowCheat {
+		if err := r.db.Model(&newWorld).Update("allow_cheat", false).Error; err != nil {
+			return nil, err
+		}
+	}
 	return &dto.ServerParams{
 		Name:                    newWorld.Name,
 		Port:                    newWorld.Port,
+		PortV6:                  newWorld.PortV6,
 		GameMode:                newWorld.GameMode,
 		Difficult:               newWorld.Difficult,
-		AllowCheat:              newWorld.AllowCheat,
+		AllowCheat:              &newWorld.AllowCheat,
 		MaxPlayer:               newWorld.MaxPlayer,
 		DefaultPermissionPlayer: newWorld.DefaultPermissionPlayer,
 		SeedWorld:               newWorld.SeedWorld,
 		ViewDistance:            req.ViewDistance,
+		RestartPolicy:           newWorld.RestartPolicy,
+		RestartMaxRetries:       newWorld.RestartMaxRetries,
+		Autostart:               &newWorld.Autostart,
+		StartOrder:              newWorld.StartOrder,
+		Runtime:                 newWorld.Runtime,
 	}, nil
 }
 
@@ -65,14 +149,17 @@ func (r *bedrockRepo) EditWorld(req *dto.ServerParams, idWorld uint) error {
 	if req.Port != 0 {
 		updates["port"] = req.Port
 	}
+	if req.PortV6 != 0 {
+		updates["port_v6"] = req.PortV6
+	}
 	if req.GameMode != "" {
 		updates["game_mode"] = req.GameMode
 	}
 	if req.Difficult != "" {
 		updates["difficult"] = req.Difficult
 	}
-	if req.AllowCheat {
-		updates["allow_cheat"] = req.AllowCheat
+	if req.AllowCheat != nil {
+		updates["allow_cheat"] = *req.AllowCheat
 	}
 	if req.MaxPlayer != 0 {
 		updates["max_player"] = req.MaxPlayer
@@ -86,6 +173,34 @@ func (r *bedrockRepo) EditWorld(req *dto.ServerParams, idWorld uint) error {
 	if req.ViewDistance != 0 {
 		updates["view_distance"] = req.ViewDistance
 	}
+	if req.RestartPolicy != "" {
+		updates["restart_policy"] = req.RestartPolicy
+	}
+	if req.RestartMaxRetries != 0 {
+		updates["restart_max_retries"] = req.RestartMaxRetries
+	}
+	if req.Autostart != nil {
+		updates["autostart"] = *req.Autostart
+	}
+	if req.StartOrder != 0 {
+		updates["start_order"] = req.StartOrder
+	}
+	if req.Runtime != "" {
+		updates["runtime"] = req.Runtime
+	}
+	// -1 removes a limit, 0 leaves it unchanged
+	if req.CPUQuota != 0 {
+		updates["cpu_quota"] = max(req.CPUQuota, 0)
+	}
+	if req.MemoryMaxMB != 0 {
+		updates["memory_max_mb"] = max(req.MemoryMaxMB, 0)
+	}
+	if req.PidsMax != 0 {
+		updates["pids_max"] = max(req.PidsMax, 0)
+	}
+	if req.IdleShutdownMinutes != 0 {
+		updates["idle_shutdown_minutes"] = max(req.IdleShutdownMinutes, 0)
+	}
 
 	if len(updates) == 0 {
 		return nil
@@ -130,6 +245,38 @@ func (u *bedrockRepo) EnsurePlayerExists(xuid string, worldId uint) error {
 	return nil
 }
 
+func (r *bedrockRepo) GetWorldByName(name string) (*model.WorldServer, error) {
+	var world model.WorldServer
+	if err := r.db.Model(&model.WorldServer{}).Where("name = ?", name).First(&world).Error; err != nil {
+		return nil, err
+	}
+	return &world, nil
+}
+
+// UpdateWorldProperties mirrors server.properties edits into the world row.
+func (r *bedrockRepo) UpdateWorldProperties(id uint, updates map[string]interface{}) error {
+	if len(updates) == 0 {
+		return nil
+	}
+	return r.db.Model(&model.WorldServer{}).Where("id = ?", id).Updates(updates).Error
+}
+
+func (r *bedrockRepo) GetAutostartWorlds() ([]model.WorldServer, error) {
+	var worlds []model.WorldServer
+	if err := r.db.Model(&model.WorldServer{}).Where("autostart = ?", true).Order("start_order, id").Find(&worlds).Error; err != nil {
+		return nil, err
+	}
+	return worlds, nil
+}
+
+func (r *bedrockRepo) GetWorldPorts() ([]model.WorldServer, error) {
+	var worlds []model.WorldServer
+	if err := r.db.Model(&model.WorldServer{}).Select("id", "name", "port", "port_v6").Find(&worlds).Error; err != nil {
+		return nil, err
+	}
+	return worlds, nil
+}
+
 func (r *bedrockRepo) GetWorlds() ([]dto.GetWorlds, error) {
 	var result []model.WorldServer
 	if err := r.db.Model(&model.WorldServer{}).Preload("MemberRole").Preload("User").Find(&result).Error; err != nil {
@@ -144,6 +291,7 @@ func (r *bedrockRepo) GetWorlds() ([]dto.GetWorlds, error) {
 			Creator: r.User.Username,
 			Name:    r.Name,
 			Port:    r.Port,
+			PortV6:  r.PortV6,
 			Players: len(r.MemberRole),
 		})
 
@@ -159,10 +307,24 @@ func (r *bedrockRepo) GetWorldAndPlayers(name string) (*dto.GetWorldAndPlayers,
 		return nil, err
 	}
 
+	xuids := make([]string, 0, len(result.MemberRole))
+	for _, r := range result.MemberRole {
+		xuids = append(xuids, r.Xuid)
+	}
+	var known []model.Player
+	if err := r.db.Select("xuid, gamertag").Where("xuid IN ?", xuids).Find(&known).Error; err != nil {
+		return nil, err
+	}
+	gamertags := make(map[string]string, len(known))
+	for _, p := range known {
+		gamertags[p.Xuid] = p.Gamertag
+	}
+
 	var responsePlayers []dto.Player
 	for _, r := range result.MemberRole {
 		responsePlayers = append(responsePlayers, dto.Player{
 			Xuid: r.Xuid,
+			Name: gamertags[r.Xuid],
 		})
 	}
 
@@ -171,6 +333,7 @@ func (r *bedrockRepo) GetWorldAndPlayers(name string) (*dto.GetWorldAndPlayers,
 		Creator:                 result.User.Username,
 		Name:                    result.Name,
 		Port:                    result.Port,
+		PortV6:                  result.PortV6,
 		Difficult:               result.Difficult,
 		GameMode:                result.GameMode,
 		MaxPlayer:               result.MaxPlayer,
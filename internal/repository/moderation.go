@@ -0,0 +1,89 @@
+package repository
+
+import (
+	"minecrat_go/model"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const actionMute = "mute"
+
+func activeMutes(db *gorm.DB, at time.Time) *gorm.DB {
+	return db.Where("action = ? AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > ?)", actionMute, at)
+}
+
+func (r *bedrockRepo) CreateModeration(action *model.ModerationAction) error {
+	return r.db.Create(action).Error
+}
+
+func (r *bedrockRepo) GetActiveMute(xuid string, worldId uint, at time.Time) (*model.ModerationAction, error) {
+	var mute model.ModerationAction
+	err := activeMutes(r.db, at).
+		Where("xuid = ? AND world_server_id = ?", xuid, worldId).
+		Order("created_at DESC, id DESC").
+		First(&mute).Error
+	if err != nil {
+		return nil, err
+	}
+	return &mute, nil
+}
+
+// LiftMute ends a mute, pending tells whether the unmute still has to reach
+// the server.
+func (r *bedrockRepo) LiftMute(id uint, at time.Time, pending bool) error {
+	result := r.db.Model(&model.ModerationAction{}).Where("id = ? AND lifted_at IS NULL", id).Updates(map[string]interface{}{
+		"lifted_at": at,
+		"pending":   pending,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+	return nil
+}
+
+func (r *bedrockRepo) SetModerationPending(id uint, pending bool) error {
+	return r.db.Model(&model.ModerationAction{}).Where("id = ?", id).Update("pending", pending).Error
+}
+
+// GetPendingMutes returns the mutes of a player on a world whose state the
+// server has not applied yet, oldest first.
+func (r *bedrockRepo) GetPendingMutes(xuid string, worldId uint) ([]model.ModerationAction, error) {
+	var mutes []model.ModerationAction
+	err := r.db.Where("action = ? AND pending = ? AND xuid = ? AND world_server_id = ?", actionMute, true, xuid, worldId).
+		Order("created_at, id").
+		Find(&mutes).Error
+	if err != nil {
+		return nil, err
+	}
+	return mutes, nil
+}
+
+func (r *bedrockRepo) GetExpiredMutes(at time.Time) ([]model.ModerationAction, error) {
+	var mutes []model.ModerationAction
+	err := r.db.Preload("WorldServer").
+		Where("action = ? AND lifted_at IS NULL AND expires_at IS NOT NULL AND expires_at <= ?", actionMute, at).
+		Find(&mutes).Error
+	if err != nil {
+		return nil, err
+	}
+	return mutes, nil
+}
+
+// GetModerationHistory returns the actions against xuid newest first, on one
+// world or on all of them when worldId is 0.
+func (r *bedrockRepo) GetModerationHistory(xuid string, worldId uint) ([]model.ModerationAction, error) {
+	db := r.db.Preload("WorldServer").Where("xuid = ?", xuid)
+	if worldId != 0 {
+		db = db.Where("world_server_id = ?", worldId)
+	}
+
+	var actions []model.ModerationAction
+	if err := db.Order("created_at DESC, id DESC").Find(&actions).Error; err != nil {
+		return nil, err
+	}
+	return actions, nil
+}
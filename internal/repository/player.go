@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"errors"
+	"minecrat_go/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// UpsertPlayer records that xuid connected as gamertag, keeping the name
+// history of the account.
+func (r *bedrockRepo) UpsertPlayer(xuid, gamertag string, at time.Time) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var player model.Player
+		err := tx.Where("xuid = ?", xuid).First(&player).Error
+		switch {
+		case errors.Is(err, gorm.ErrRecordNotFound):
+			player = model.Player{Xuid: xuid, Gamertag: gamertag, FirstSeen: at, LastSeen: at}
+			if err := tx.Create(&player).Error; err != nil {
+				return err
+			}
+		case err != nil:
+			return err
+		default:
+			if err := tx.Model(&player).Updates(map[string]interface{}{"gamertag": gamertag, "last_seen": at}).Error; err != nil {
+				return err
+			}
+		}
+
+		var name model.PlayerName
+		err = tx.Where("xuid = ? AND gamertag = ?", xuid, gamertag).First(&name).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return tx.Create(&model.PlayerName{Xuid: xuid, Gamertag: gamertag, FirstSeen: at, LastSeen: at}).Error
+		}
+		if err != nil {
+			return err
+		}
+		return tx.Model(&name).Update("last_seen", at).Error
+	})
+}
+
+func (r *bedrockRepo) GetPlayer(xuid string) (*model.Player, error) {
+	var player model.Player
+	err := r.db.Preload("Names", func(db *gorm.DB) *gorm.DB {
+		return db.Order("last_seen DESC")
+	}).Where("xuid = ?", xuid).First(&player).Error
+	if err != nil {
+		return nil, err
+	}
+	return &player, nil
+}
+
+func (r *bedrockRepo) GetPlayersByGamertag(gamertag string) ([]model.Player, error) {
+	var players []model.Player
+	if err := r.db.Where("gamertag = ?", gamertag).Find(&players).Error; err != nil {
+		return nil, err
+	}
+	return players, nil
+}
+
+// GetPlayersByPastGamertag finds the accounts that used gamertag before.
+func (r *bedrockRepo) GetPlayersByPastGamertag(gamertag string) ([]model.Player, error) {
+	var players []model.Player
+	err := r.db.Where("xuid IN (?)", r.db.Model(&model.PlayerName{}).Select("xuid").Where("gamertag = ?", gamertag)).
+		Find(&players).Error
+	if err != nil {
+		return nil, err
+	}
+	return players, nil
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+
+// SearchPlayers pages the directory by gamertag prefix or exact xuid, most
+// recently seen first. An empty query lists everyone.
+func (r *bedrockRepo) SearchPlayers(query string, limit, offset int) ([]model.Player, int64, error) {
+	db := r.db.Model(&model.Player{})
+	if query != "" {
+		prefix := likeEscaper.Replace(query) + "%"
+		db = db.Where("gamertag LIKE ? OR xuid = ?", prefix, query)
+	}
+
+	var total int64
+	if err := db.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var players []model.Player
+	if err := db.Order("last_seen DESC").Limit(limit).Offset(offset).Find(&players).Error; err != nil {
+		return nil, 0, err
+	}
+	return players, total, nil
+}
@@ -0,0 +1,49 @@
+package repository
+
+import (
+	"minecrat_go/model"
+	"time"
+)
+
+func (r *bedrockRepo) CreateSchedule(schedule *model.WorldSchedule) error {
+	return r.db.Create(schedule).Error
+}
+
+func (r *bedrockRepo) UpdateSchedule(schedule *model.WorldSchedule) error {
+	return r.db.Select("action", "cron", "warn_seconds", "enabled").Updates(schedule).Error
+}
+
+func (r *bedrockRepo) DeleteSchedule(worldId, id uint) error {
+	return r.db.Where("id = ? AND world_server_id = ?", id, worldId).Delete(&model.WorldSchedule{}).Error
+}
+
+func (r *bedrockRepo) GetSchedule(worldId, id uint) (*model.WorldSchedule, error) {
+	var schedule model.WorldSchedule
+	if err := r.db.Where("id = ? AND world_server_id = ?", id, worldId).First(&schedule).Error; err != nil {
+		return nil, err
+	}
+	return &schedule, nil
+}
+
+func (r *bedrockRepo) GetSchedules(worldId uint) ([]model.WorldSchedule, error) {
+	var schedules []model.WorldSchedule
+	if err := r.db.Where("world_server_id = ?", worldId).Order("id").Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (r *bedrockRepo) GetEnabledSchedules() ([]model.WorldSchedule, error) {
+	var schedules []model.WorldSchedule
+	if err := r.db.Preload("WorldServer").Where("enabled = ?", true).Find(&schedules).Error; err != nil {
+		return nil, err
+	}
+	return schedules, nil
+}
+
+func (r *bedrockRepo) SetScheduleRun(id uint, at time.Time, runErr string) error {
+	return r.db.Model(&model.WorldSchedule{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"last_run_at": at,
+		"last_error":  runErr,
+	}).Error
+}
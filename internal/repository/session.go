@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"minecrat_go/dto"
+	"minecrat_go/model"
+	"time"
+)
+
+func (r *bedrockRepo) CreateSession(session *model.PlayerSession) error {
+	return r.db.Create(session).Error
+}
+
+func (r *bedrockRepo) CloseSession(id uint, leftAt time.Time, seconds int64, reason string) error {
+	return r.db.Model(&model.PlayerSession{}).Where("id = ? AND left_at IS NULL", id).Updates(map[string]interface{}{
+		"left_at":    leftAt,
+		"seconds":    seconds,
+		"end_reason": reason,
+	}).Error
+}
+
+func (r *bedrockRepo) GetOpenSessions() ([]model.PlayerSession, error) {
+	var sessions []model.PlayerSession
+	if err := r.db.Preload("WorldServer").Where("left_at IS NULL").Order("joined_at").Find(&sessions).Error; err != nil {
+		return nil, err
+	}
+	return sessions, nil
+}
+
+// GetSessions pages the sessions of one player on a world, newest first.
+func (r *bedrockRepo) GetSessions(worldId uint, xuid string, limit, offset int) ([]model.PlayerSession, int64, error) {
+	query := r.db.Model(&model.PlayerSession{}).Where("world_server_id = ? AND xuid = ?", worldId, xuid)
+
+	var total int64
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+
+	var sessions []model.PlayerSession
+	if err := query.Order("joined_at DESC").Limit(limit).Offset(offset).Find(&sessions).Error; err != nil {
+		return nil, 0, err
+	}
+	return sessions, total, nil
+}
+
+// GetPlaytime sums the closed sessions of every player of a world, the name
+// is the one of the latest session.
+func (r *bedrockRepo) GetPlaytime(worldId uint) ([]dto.Playtime, error) {
+	var rows []struct {
+		Xuid     string
+		Sessions int64
+		Seconds  int64
+		LastSeen time.Time
+	}
+	err := r.db.Model(&model.PlayerSession{}).
+		Select("xuid, COUNT(*) AS sessions, COALESCE(SUM(seconds), 0) AS seconds, MAX(COALESCE(left_at, joined_at)) AS last_seen").
+		Where("world_server_id = ?", worldId).
+		Group("xuid").
+		Order("seconds DESC").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var latest []model.PlayerSession
+	err = r.db.Select("xuid, name").
+		Where("id IN (?)", r.db.Model(&model.PlayerSession{}).Select("MAX(id)").Where("world_server_id = ?", worldId).Group("xuid")).
+		Find(&latest).Error
+	if err != nil {
+		return nil, err
+	}
+	names := make(map[string]string, len(latest))
+	for _, s := range latest {
+		names[s.Xuid] = s.Name
+	}
+
+	response := make([]dto.Playtime, 0, len(rows))
+	for _, row := range rows {
+		response = append(response, dto.Playtime{
+			Xuid:     row.Xuid,
+			Name:     names[row.Xuid],
+			Sessions: row.Sessions,
+			Seconds:  row.Seconds,
+			LastSeen: row.LastSeen,
+		})
+	}
+	return response, nil
+}
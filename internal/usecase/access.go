@@ -0,0 +1,391 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"minecrat_go/model"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Reconcile sources of ReconcileAccess.
+const (
+	AccessFromDB   = "db"
+	AccessFromFile = "file"
+)
+
+func toPermissionDTOs(permissions []model.Permission) []dto.PermissionPlayer {
+	list := make([]dto.PermissionPlayer, 0, len(permissions))
+	for _, p := range permissions {
+		list = append(list, dto.PermissionPlayer{Xuid: p.Xuid, Permission: p.Permission})
+	}
+	return list
+}
+
+func toAllowlistDTOs(entries []model.AllowlistEntry) []dto.Allowlist {
+	list := make([]dto.Allowlist, 0, len(entries))
+	for _, e := range entries {
+		list = append(list, dto.Allowlist{Xuid: e.Xuid, Name: e.Name, Priority: e.IgnoresPlayerLimit})
+	}
+	return list
+}
+
+func (u *bedrockUC) worldPermissions(worldId uint) ([]dto.PermissionPlayer, error) {
+	permissions, err := u.bedRepo.GetPermissions(worldId)
+	if err != nil {
+		return nil, err
+	}
+	return toPermissionDTOs(permissions), nil
+}
+
+func (u *bedrockUC) worldAllowlist(worldId uint) ([]dto.Allowlist, error) {
+	entries, err := u.bedRepo.GetAllowlist(worldId)
+	if err != nil {
+		return nil, err
+	}
+	return toAllowlistDTOs(entries), nil
+}
+
+// accessRenderer renders a file of a world from the database.
+func (u *bedrockUC) accessRenderer(worldId uint, file string) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		if file == permissionsFile {
+			list, err := u.worldPermissions(worldId)
+			if err != nil {
+				return nil, err
+			}
+			return encodeList(list)
+		}
+		list, err := u.worldAllowlist(worldId)
+		if err != nil {
+			return nil, err
+		}
+		return encodeList(list)
+	}
+}
+
+func (u *bedrockUC) reloadAccessFile(world, file string) {
+	if file == permissionsFile {
+		u.reloadPermissions(world)
+		return
+	}
+	u.reloadAllowlist(world)
+}
+
+// editAccess runs change against the rows behind a file of a world, renders
+// the file again and makes a running server reload it. The etag of the file
+// is returned.
+func (u *bedrockUC) editAccess(world, file, ifMatch string, change func(worldId uint) (bool, error)) (string, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(world)
+	if err != nil {
+		return "", fmt.Errorf("server %s not found", world)
+	}
+
+	changed := false
+	etag, err := u.files.edit(world, file, ifMatch, u.accessRenderer(worlddb.ID, file), func() (bool, error) {
+		var err error
+		changed, err = change(worlddb.ID)
+		return changed, err
+	})
+	if err != nil {
+		return "", err
+	}
+	if changed {
+		u.reloadAccessFile(world, file)
+	}
+	return etag, nil
+}
+
+// writeAccessFiles renders permissions.json and allowlist.json of a world
+// from the database.
+func (u *bedrockUC) writeAccessFiles(world string, worldId uint) error {
+	for _, file := range []string{permissionsFile, allowlistFile} {
+		if err := u.files.write(world, file, u.accessRenderer(worldId, file)); err != nil {
+			return fmt.Errorf("render %s: %w", file, err)
+		}
+	}
+	return nil
+}
+
+// syncAccessFiles renders the files of a world before it starts, hand edits
+// made since the last render are reported and overwritten.
+func (u *bedrockUC) syncAccessFiles(world string, worldId uint) {
+	if drift, err := u.accessDrift(world, worldId); err == nil && !drift.InSync {
+		log.Printf("server %s: permissions.json or allowlist.json differ from the database, rewriting them", world)
+		u.events.publish(world, dto.EventAccessDrift, drift)
+	}
+	if err := u.writeAccessFiles(world, worldId); err != nil {
+		log.Printf("server %s: %s", world, err)
+	}
+}
+
+// filePermissions turns the entries of a permissions.json into rows. A xuid
+// listed twice keeps its last entry, the unique index allows only one row,
+// and entries without xuid are dropped.
+func filePermissions(list []dto.PermissionPlayer) []model.Permission {
+	index := make(map[string]int, len(list))
+	permissions := make([]model.Permission, 0, len(list))
+	for _, p := range list {
+		xuid := strings.TrimSpace(p.Xuid)
+		if xuid == "" {
+			continue
+		}
+		if i, ok := index[xuid]; ok {
+			permissions[i].Permission = p.Permission
+			continue
+		}
+		index[xuid] = len(permissions)
+		permissions = append(permissions, model.Permission{Xuid: xuid, Permission: p.Permission})
+	}
+	return permissions
+}
+
+// importAccessFile replaces the rows behind a file of a world with what the
+// file holds, a missing file counts as empty when missingOk is set.
+func (u *bedrockUC) importAccessFile(world string, worldId uint, file string, missingOk bool) error {
+	_, err := u.files.edit(world, file, "", u.accessRenderer(worldId, file), func() (bool, error) {
+		if file == permissionsFile {
+			var list []dto.PermissionPlayer
+			if err := readList(world, file, &list); err != nil && !(missingOk && os.IsNotExist(err)) {
+				return false, err
+			}
+			return true, u.bedRepo.ReplacePermissions(worldId, filePermissions(list))
+		}
+
+		var list []dto.Allowlist
+		if err := readList(world, file, &list); err != nil && !(missingOk && os.IsNotExist(err)) {
+			return false, err
+		}
+		entries := make([]model.AllowlistEntry, 0, len(list))
+		for _, e := range list {
+			entries = append(entries, model.AllowlistEntry{Xuid: e.Xuid, Name: e.Name, IgnoresPlayerLimit: e.Priority})
+		}
+		return true, u.bedRepo.ReplaceAllowlist(worldId, entries)
+	})
+	return err
+}
+
+// importAccessFiles moves the files of the worlds made before the
+// permission tables existed into the database, once per world.
+func (u *bedrockUC) importAccessFiles() {
+	worlds, err := u.bedRepo.GetUnimportedWorlds()
+	if err != nil {
+		log.Printf("import permissions and allowlists failed: %s", err)
+		return
+	}
+	for _, world := range worlds {
+		ok := true
+		for _, file := range []string{permissionsFile, allowlistFile} {
+			if err := u.importAccessFile(world.Name, world.ID, file, true); err != nil {
+				log.Printf("server %s: import %s failed: %s", world.Name, file, err)
+				ok = false
+			}
+		}
+		if !ok {
+			continue
+		}
+		if err := u.bedRepo.UpdateWorldProperties(world.ID, map[string]interface{}{"access_imported": true}); err != nil {
+			log.Printf("server %s: import permissions and allowlist failed: %s", world.Name, err)
+			continue
+		}
+		log.Printf("server %s: permissions and allowlist imported into the database", world.Name)
+	}
+}
+
+func permissionDrift(db, file []dto.PermissionPlayer) ([]dto.PermissionPlayer, []dto.PermissionPlayer) {
+	contains := func(list []dto.PermissionPlayer, p dto.PermissionPlayer) bool {
+		for _, q := range list {
+			if q.Xuid == p.Xuid && q.Permission == p.Permission {
+				return true
+			}
+		}
+		return false
+	}
+
+	var onlyDB, onlyFile []dto.PermissionPlayer
+	for _, p := range db {
+		if !contains(file, p) {
+			onlyDB = append(onlyDB, p)
+		}
+	}
+	for _, p := range file {
+		if !contains(db, p) {
+			onlyFile = append(onlyFile, p)
+		}
+	}
+	return onlyDB, onlyFile
+}
+
+// allowlistMatches compares an entry of the database with one of the file.
+// bedrock_server fills in the xuid of a name-only entry once the player
+// joins, that does not count as drift.
+func allowlistMatches(db, file dto.Allowlist) bool {
+	if db.Priority != file.Priority {
+		return false
+	}
+	if db.Xuid != "" {
+		return db.Xuid == file.Xuid
+	}
+	return strings.EqualFold(db.Name, file.Name)
+}
+
+func allowlistDrift(db, file []dto.Allowlist) ([]dto.Allowlist, []dto.Allowlist) {
+	var onlyDB, onlyFile []dto.Allowlist
+	for _, d := range db {
+		found := false
+		for _, f := range file {
+			if allowlistMatches(d, f) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			onlyDB = append(onlyDB, d)
+		}
+	}
+	for _, f := range file {
+		found := false
+		for _, d := range db {
+			if allowlistMatches(d, f) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			onlyFile = append(onlyFile, f)
+		}
+	}
+	return onlyDB, onlyFile
+}
+
+func (u *bedrockUC) accessDrift(world string, worldId uint) (*dto.AccessDrift, error) {
+	drift := &dto.AccessDrift{World: world}
+
+	permissions, err := u.worldPermissions(worldId)
+	if err != nil {
+		return nil, err
+	}
+	var filePermissions []dto.PermissionPlayer
+	if err := u.files.read(world, permissionsFile, &filePermissions); err != nil {
+		drift.Permissions.Error = err.Error()
+	} else {
+		drift.Permissions.OnlyInDB, drift.Permissions.OnlyInFile = permissionDrift(permissions, filePermissions)
+	}
+
+	allowlist, err := u.worldAllowlist(worldId)
+	if err != nil {
+		return nil, err
+	}
+	var fileAllowlist []dto.Allowlist
+	if err := u.files.read(world, allowlistFile, &fileAllowlist); err != nil {
+		drift.Allowlist.Error = err.Error()
+	} else {
+		drift.Allowlist.OnlyInDB, drift.Allowlist.OnlyInFile = allowlistDrift(allowlist, fileAllowlist)
+	}
+
+	drift.InSync = drift.Permissions.Error == "" && drift.Allowlist.Error == "" &&
+		len(drift.Permissions.OnlyInDB)+len(drift.Permissions.OnlyInFile)+len(drift.Allowlist.OnlyInDB)+len(drift.Allowlist.OnlyInFile) == 0
+	return drift, nil
+}
+
+// GetAccessDrift compares permissions.json and allowlist.json of a world with
+// the database.
+func (u *bedrockUC) GetAccessDrift(name string) (*dto.AccessDrift, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+	return u.accessDrift(name, worlddb.ID)
+}
+
+// ReconcileAccess settles the drift of a world. With AccessFromDB the files
+// are rendered again, with AccessFromFile the hand edits are taken into the
+// database. The drift found before is returned.
+func (u *bedrockUC) ReconcileAccess(name, source string) (*dto.AccessDrift, error) {
+	if source == "" {
+		source = AccessFromDB
+	}
+	if source != AccessFromDB && source != AccessFromFile {
+		return nil, fmt.Errorf("%w: source must be %s or %s", utils.ErrInvalidQuery, AccessFromDB, AccessFromFile)
+	}
+
+	worlddb, err := u.bedRepo.GetWorldByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+	drift, err := u.accessDrift(name, worlddb.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, file := range []string{permissionsFile, allowlistFile} {
+		if source == AccessFromFile {
+			err = u.importAccessFile(name, worlddb.ID, file, false)
+		} else {
+			err = u.files.write(name, file, u.accessRenderer(worlddb.ID, file))
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reconcile %s: %w", file, err)
+		}
+		u.reloadAccessFile(name, file)
+	}
+	return drift, nil
+}
+
+// detectAccessDrift reports the worlds whose files were edited by hand.
+func (u *bedrockUC) detectAccessDrift() {
+	worlds, err := u.bedRepo.GetWorldPorts()
+	if err != nil {
+		log.Printf("detect access drift failed: %s", err)
+		return
+	}
+	for _, world := range worlds {
+		drift, err := u.accessDrift(world.Name, world.ID)
+		if err != nil || drift.InSync {
+			continue
+		}
+		log.Printf("server %s: permissions.json or allowlist.json differ from the database", world.Name)
+		u.events.publish(world.Name, dto.EventAccessDrift, drift)
+	}
+}
+
+// playerAccess lists the permissions and allowlist entries of xuid per
+// world.
+func (u *bedrockUC) playerAccess(xuid string) ([]dto.PlayerAccess, error) {
+	permissions, entries, err := u.bedRepo.GetPlayerAccess(xuid)
+	if err != nil {
+		return nil, err
+	}
+
+	byWorld := make(map[string]*dto.PlayerAccess)
+	get := func(world *model.WorldServer) *dto.PlayerAccess {
+		name := ""
+		if world != nil {
+			name = world.Name
+		}
+		access, ok := byWorld[name]
+		if !ok {
+			access = &dto.PlayerAccess{World: name}
+			byWorld[name] = access
+		}
+		return access
+	}
+	for _, p := range permissions {
+		get(p.WorldServer).Permission = p.Permission
+	}
+	for _, e := range entries {
+		access := get(e.WorldServer)
+		access.Allowlisted = true
+		access.IgnoresPlayerLimit = e.IgnoresPlayerLimit
+	}
+
+	result := make([]dto.PlayerAccess, 0, len(byWorld))
+	for _, access := range byWorld {
+		result = append(result, *access)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].World < result[j].World })
+	return result, nil
+}
@@ -0,0 +1,54 @@
+package usecase
+
+import (
+	"minecrat_go/dto"
+	"minecrat_go/model"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestFilePermissions(t *testing.T) {
+	list := []dto.PermissionPlayer{
+		{Xuid: "2535400000000001", Permission: "member"},
+		{Xuid: "2535400000000002", Permission: "operator"},
+		{Xuid: "", Permission: "operator"},
+		{Xuid: " 2535400000000001 ", Permission: "operator"},
+		{Xuid: "2535400000000002", Permission: "visitor"},
+	}
+	want := []model.Permission{
+		{Xuid: "2535400000000001", Permission: "operator"},
+		{Xuid: "2535400000000002", Permission: "visitor"},
+	}
+	if got := filePermissions(list); !reflect.DeepEqual(got, want) {
+		t.Errorf("filePermissions = %+v, want %+v", got, want)
+	}
+}
+
+func TestImportPermissionsWithDuplicates(t *testing.T) {
+	u, repo := newTestUC(t, NewFakeRuntime(), testWorld("alpha", 1))
+	file := `[
+  {"xuid": "2535400000000001", "permission": "member"},
+  {"xuid": "2535400000000001", "permission": "operator"}
+]`
+	if err := os.WriteFile(worldFilePath("alpha", permissionsFile), []byte(file), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := u.importAccessFile("alpha", 1, permissionsFile, false); err != nil {
+		t.Fatal(err)
+	}
+	rows, _ := repo.GetPermissions(1)
+	if len(rows) != 1 || rows[0].Permission != "operator" {
+		t.Errorf("rows = %+v, want the last entry only", rows)
+	}
+
+	// the file is rewritten from the database without the duplicate
+	var written []dto.PermissionPlayer
+	if err := readList("alpha", permissionsFile, &written); err != nil {
+		t.Fatal(err)
+	}
+	if len(written) != 1 || written[0].Permission != "operator" {
+		t.Errorf("permissions.json = %+v, want the last entry only", written)
+	}
+}
@@ -0,0 +1,174 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"minecrat_go/model"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// allowlistEntry resolves the player an allowlist entry names, either field
+// may name it and the other one is filled in. A player banned on the world
+// is refused.
+func (u *bedrockUC) allowlistEntry(worldId uint, req dto.Allowlist) (dto.Allowlist, error) {
+	id := req.Xuid
+	if id == "" {
+		id = req.Name
+	}
+	if id == "" {
+		return req, fmt.Errorf("%w: xuid or name is required", utils.ErrInvalidAllowlist)
+	}
+
+	if player, err := u.resolvePlayer(id); err == nil {
+		req.Xuid, req.Name = player.Xuid, player.Gamertag
+	} else if !errors.Is(err, utils.ErrPlayerNotFound) {
+		return req, err
+	} else if req.Xuid != "" && !isXuid(req.Xuid) {
+		// an unknown gamertag, bedrock matches name-only entries on join
+		req.Name, req.Xuid = req.Xuid, ""
+	}
+	if req.Xuid != "" {
+		if ban, err := u.bedRepo.GetActiveBan(req.Xuid, worldId, time.Now()); err == nil {
+			return req, fmt.Errorf("%w: ban %d", utils.ErrPlayerBanned, ban.ID)
+		}
+	}
+	return req, nil
+}
+
+// sameAllowlistEntry matches entries by xuid, name-only entries by gamertag.
+func sameAllowlistEntry(a, b dto.Allowlist) bool {
+	if a.Xuid != "" || b.Xuid != "" {
+		return a.Xuid == b.Xuid
+	}
+	return strings.EqualFold(a.Name, b.Name)
+}
+
+func (u *bedrockUC) allowlistEnabled(name string) (bool, error) {
+	u.propsMu.Lock()
+	defer u.propsMu.Unlock()
+
+	p, err := readProperties(name)
+	if err != nil {
+		return false, err
+	}
+	value, _ := p.get("allow-list")
+	enabled, _ := strconv.ParseBool(value)
+	return enabled, nil
+}
+
+// GetAllowlist returns the allowlist of a world, whether server.properties
+// enforces it and the etag of allowlist.json.
+func (u *bedrockUC) GetAllowlist(name string) (*dto.AllowlistState, string, error) {
+	entries, etag, err := u.GetPriority(name)
+	if err != nil {
+		return nil, "", err
+	}
+	enabled, err := u.allowlistEnabled(name)
+	if err != nil {
+		return nil, "", err
+	}
+	return &dto.AllowlistState{World: name, Enabled: enabled, Entries: entries}, etag, nil
+}
+
+// SetAllowlistEnabled turns allow-list in server.properties on or off, a
+// running world follows right away.
+func (u *bedrockUC) SetAllowlistEnabled(name string, enabled bool) (*dto.ApplyResult, error) {
+	props, err := u.PatchProperties(name, map[string]json.RawMessage{
+		"allow-list": json.RawMessage(strconv.FormatBool(enabled)),
+	}, false)
+	if err != nil {
+		return nil, err
+	}
+	return &props.ApplyResult, nil
+}
+
+// RemoveFromAllowlist takes a player off the allowlist of a world, a
+// gamertag also removes the entries without xuid of that name.
+func (u *bedrockUC) RemoveFromAllowlist(name, player, ifMatch string) (string, error) {
+	xuid, gamertag := "", player
+	if p, err := u.resolvePlayer(player); err == nil {
+		xuid, gamertag = p.Xuid, p.Gamertag
+	} else if !errors.Is(err, utils.ErrPlayerNotFound) {
+		return "", err
+	} else if isXuid(player) {
+		xuid, gamertag = player, ""
+	}
+
+	removed := false
+	etag, err := u.editAccess(name, allowlistFile, ifMatch, func(worldId uint) (bool, error) {
+		entries, err := u.bedRepo.DeleteAllowlistEntries(worldId, xuid, gamertag)
+		removed = len(entries) > 0
+		return removed, err
+	})
+	if err != nil {
+		return "", err
+	}
+	if !removed {
+		return "", fmt.Errorf("%w: %s is not on the allowlist of %s", utils.ErrPlayerNotFound, player, name)
+	}
+	return etag, nil
+}
+
+// ReplaceAllowlist makes entries the whole allowlist of a world. Entries
+// naming nobody, an ambiguous gamertag or a player banned on the world are
+// skipped, a player listed twice keeps the last entry.
+func (u *bedrockUC) ReplaceAllowlist(name string, entries []dto.Allowlist, ifMatch string) (*dto.AllowlistImport, string, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(name)
+	if err != nil {
+		return nil, "", fmt.Errorf("server %s not found", name)
+	}
+
+	result := &dto.AllowlistImport{Entries: []dto.Allowlist{}, Skipped: []dto.AllowlistSkip{}}
+	for _, e := range entries {
+		entry, err := u.allowlistEntry(worlddb.ID, e)
+		if errors.Is(err, utils.ErrInvalidAllowlist) || errors.Is(err, utils.ErrAmbiguousPlayer) || errors.Is(err, utils.ErrPlayerBanned) {
+			result.Skipped = append(result.Skipped, dto.AllowlistSkip{Entry: e, Reason: err.Error()})
+			continue
+		}
+		if err != nil {
+			return nil, "", err
+		}
+
+		replaced := false
+		for i := range result.Entries {
+			if sameAllowlistEntry(result.Entries[i], entry) {
+				result.Entries[i] = entry
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			result.Entries = append(result.Entries, entry)
+		}
+	}
+
+	rows := make([]model.AllowlistEntry, 0, len(result.Entries))
+	for _, e := range result.Entries {
+		rows = append(rows, model.AllowlistEntry{Xuid: e.Xuid, Name: e.Name, IgnoresPlayerLimit: e.Priority})
+	}
+	etag, err := u.editAccess(name, allowlistFile, ifMatch, func(worldId uint) (bool, error) {
+		return true, u.bedRepo.ReplaceAllowlist(worldId, rows)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	return result, etag, nil
+}
+
+// CopyAllowlist replaces the allowlist of a world with the one of another
+// world.
+func (u *bedrockUC) CopyAllowlist(name, from, ifMatch string) (*dto.AllowlistImport, string, error) {
+	if from == "" || from == name {
+		return nil, "", fmt.Errorf("%w: copy needs another world to copy from", utils.ErrInvalidAllowlist)
+	}
+	entries, _, err := u.GetPriority(from)
+	if err != nil {
+		return nil, "", err
+	}
+	return u.ReplaceAllowlist(name, entries, ifMatch)
+}
@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"errors"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"minecrat_go/model"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// newAllowlistUC knows Steve, Alex who is banned, and two players that
+// both go by Sam.
+func newAllowlistUC(t *testing.T) (*bedrockUC, *stubRepo) {
+	t.Helper()
+	u, repo := newTestUC(t, NewFakeRuntime(), testWorld("alpha", 1), testWorld("beta", 2))
+	now := time.Now()
+	repo.UpsertPlayer("2535400000000001", "Steve", now)
+	repo.UpsertPlayer("2535400000000002", "Alex", now)
+	repo.UpsertPlayer("2535400000000003", "Sam", now)
+	repo.UpsertPlayer("2535400000000004", "Sam", now)
+	repo.bans["2535400000000002"] = &model.Ban{ID: 7, Xuid: "2535400000000002"}
+	return u, repo
+}
+
+func TestReplaceAllowlist(t *testing.T) {
+	steve := dto.Allowlist{Xuid: "2535400000000001", Name: "Steve"}
+
+	tests := []struct {
+		name    string
+		entries []dto.Allowlist
+		want    []dto.Allowlist
+		skipped int
+	}{
+		{"gamertag gets its xuid", []dto.Allowlist{{Xuid: "Steve"}}, []dto.Allowlist{steve}, 0},
+		{"xuid gets its gamertag", []dto.Allowlist{{Xuid: "2535400000000001"}}, []dto.Allowlist{steve}, 0},
+		{"name field resolves too", []dto.Allowlist{{Name: "steve"}}, []dto.Allowlist{steve}, 0},
+		{
+			"a player listed twice keeps the last entry",
+			[]dto.Allowlist{{Xuid: "Steve"}, {Name: "Alice"}, {Xuid: "2535400000000001", Priority: true}},
+			[]dto.Allowlist{{Xuid: "2535400000000001", Name: "Steve", Priority: true}, {Name: "Alice"}},
+			0,
+		},
+		{"unknown gamertag stays name-only", []dto.Allowlist{{Xuid: "Herobrine"}}, []dto.Allowlist{{Name: "Herobrine"}}, 0},
+		{
+			"name-only entries collapse by gamertag",
+			[]dto.Allowlist{{Name: "Herobrine"}, {Name: "herobrine", Priority: true}},
+			[]dto.Allowlist{{Name: "herobrine", Priority: true}},
+			0,
+		},
+		{"unknown xuid is kept", []dto.Allowlist{{Xuid: "2535400000009999"}}, []dto.Allowlist{{Xuid: "2535400000009999"}}, 0},
+		{"banned player is skipped", []dto.Allowlist{{Xuid: "Alex"}, {Xuid: "Steve"}}, []dto.Allowlist{steve}, 1},
+		{"ambiguous gamertag is skipped", []dto.Allowlist{{Name: "Sam"}}, []dto.Allowlist{}, 1},
+		{"empty entry is skipped", []dto.Allowlist{{}}, []dto.Allowlist{}, 1},
+		{"empty list clears the allowlist", nil, []dto.Allowlist{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, repo := newAllowlistUC(t)
+			repo.allowlist[1] = []model.AllowlistEntry{{Xuid: "2535400000000005", Name: "Old"}}
+
+			result, _, err := u.ReplaceAllowlist("alpha", tt.entries, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(result.Entries, tt.want) {
+				t.Errorf("entries = %+v, want %+v", result.Entries, tt.want)
+			}
+			if len(result.Skipped) != tt.skipped {
+				t.Errorf("skipped = %+v, want %d", result.Skipped, tt.skipped)
+			}
+
+			stored, _ := repo.GetAllowlist(1)
+			if got := toAllowlistDTOs(stored); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("stored = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCopyAllowlist(t *testing.T) {
+	u, repo := newAllowlistUC(t)
+	repo.allowlist[2] = []model.AllowlistEntry{
+		{Xuid: "2535400000000001", Name: "Steve", IgnoresPlayerLimit: true},
+		{Xuid: "2535400000000002", Name: "Alex"},
+		{Name: "Herobrine"},
+	}
+
+	result, _, err := u.CopyAllowlist("alpha", "beta", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []dto.Allowlist{
+		{Xuid: "2535400000000001", Name: "Steve", Priority: true},
+		{Name: "Herobrine"},
+	}
+	if !reflect.DeepEqual(result.Entries, want) {
+		t.Errorf("entries = %+v, want %+v", result.Entries, want)
+	}
+	if len(result.Skipped) != 1 || result.Skipped[0].Entry.Name != "Alex" {
+		t.Errorf("skipped = %+v, want the banned Alex", result.Skipped)
+	}
+	if got, _ := repo.GetAllowlist(2); len(got) != 3 {
+		t.Errorf("the source allowlist changed to %+v", got)
+	}
+
+	for _, from := range []string{"", "alpha"} {
+		if _, _, err := u.CopyAllowlist("alpha", from, ""); !errors.Is(err, utils.ErrInvalidAllowlist) {
+			t.Errorf("copy from %q: err = %v, want ErrInvalidAllowlist", from, err)
+		}
+	}
+	if _, _, err := u.CopyAllowlist("alpha", "gamma", ""); err == nil {
+		t.Error("copy from an unknown world succeeded")
+	}
+}
@@ -0,0 +1,198 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"regexp"
+	"sort"
+	"strconv"
+)
+
+// hotProperties are the server.properties keys a running server takes
+// through the console, mapped to the command that sets a value.
+var hotProperties = map[string]func(value string) string{
+	"difficulty": func(v string) string { return "difficulty " + v },
+	"gamemode":   func(v string) string { return "defaultgamemode " + v },
+	"allow-list": func(v string) string {
+		if v == "true" {
+			return "allowlist on"
+		}
+		return "allowlist off"
+	},
+}
+
+var gameruleName = regexp.MustCompile(`^[A-Za-z]+$`)
+
+// gameruleValue accepts a bool or a whole number, the only kinds of value
+// gamerules have.
+func gameruleValue(rule string, v interface{}) (string, error) {
+	if !gameruleName.MatchString(rule) {
+		return "", fmt.Errorf("%w: %q", utils.ErrInvalidRule, rule)
+	}
+	switch t := v.(type) {
+	case bool:
+		return strconv.FormatBool(t), nil
+	case float64:
+		if t == math.Trunc(t) && !math.IsInf(t, 0) {
+			return strconv.FormatInt(int64(t), 10), nil
+		}
+	case string:
+		if _, err := strconv.ParseBool(t); err == nil {
+			return t, nil
+		}
+		if _, err := strconv.Atoi(t); err == nil {
+			return t, nil
+		}
+	}
+	return "", fmt.Errorf("%w: %s must be true, false or a whole number", utils.ErrInvalidRule, rule)
+}
+
+// liveServer returns the server of a world when it is running with a console
+// the manager can write to.
+func (u *bedrockUC) liveServer(name string) *BedrockServer {
+	if state, _ := u.stateOf(name); state != StateRunning {
+		return nil
+	}
+	u.s.RLock()
+	server := u.servers[name]
+	u.s.RUnlock()
+	if server == nil || server.Adopted {
+		return nil
+	}
+	return server
+}
+
+// snapshotSettings records the server.properties a process starts with.
+func (u *bedrockUC) snapshotSettings(name string, server *BedrockServer) {
+	u.propsMu.Lock()
+	defer u.propsMu.Unlock()
+
+	p, err := readProperties(name)
+	if err != nil {
+		log.Printf("server %s: read server.properties failed: %s", name, err)
+		return
+	}
+	server.Settings = p.values()
+}
+
+// pendingRestart lists the keys of server.properties that differ from what
+// the process runs with.
+func (u *bedrockUC) pendingRestart(name string, server *BedrockServer) []string {
+	u.propsMu.Lock()
+	defer u.propsMu.Unlock()
+
+	if server.Settings == nil {
+		return nil
+	}
+	p, err := readProperties(name)
+	if err != nil {
+		return nil
+	}
+	current := p.values()
+
+	var keys []string
+	for key, value := range current {
+		if running, ok := server.Settings[key]; !ok || running != value {
+			keys = append(keys, key)
+		}
+	}
+	for key := range server.Settings {
+		if _, ok := current[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// applySettings brings a running world as close to its server.properties as
+// the console allows and reports what is left for a restart. With restart
+// the world restarts gracefully when anything is left.
+func (u *bedrockUC) applySettings(name string, gamerules map[string]string, restart bool) (*dto.ApplyResult, error) {
+	result := &dto.ApplyResult{}
+
+	u.s.RLock()
+	server := u.servers[name]
+	u.s.RUnlock()
+	if server == nil {
+		// a stopped world reads everything on its next start
+		return result, nil
+	}
+
+	rules := make([]string, 0, len(gamerules))
+	for rule := range gamerules {
+		rules = append(rules, rule)
+	}
+	sort.Strings(rules)
+	for _, rule := range rules {
+		if err := u.checkedCommand(name, fmt.Sprintf("gamerule %s %s", rule, gamerules[rule])); err != nil {
+			return nil, err
+		}
+		result.Applied = append(result.Applied, "gamerule "+rule)
+	}
+
+	live := u.liveServer(name) != nil
+	for _, key := range u.pendingRestart(name, server) {
+		command, hot := hotProperties[key]
+		if !hot || !live {
+			result.PendingRestart = append(result.PendingRestart, key)
+			continue
+		}
+
+		// a removed key falls back to the default of bedrock_server
+		value, set := propertySpecs[key].Default, false
+		u.propsMu.Lock()
+		if p, err := readProperties(name); err == nil {
+			if v, ok := p.get(key); ok {
+				value, set = v, true
+			}
+		}
+		u.propsMu.Unlock()
+
+		if err := u.checkedCommand(name, command(value)); err != nil {
+			log.Printf("server %s: apply %s failed: %s", name, key, err)
+			result.PendingRestart = append(result.PendingRestart, key)
+			continue
+		}
+		u.propsMu.Lock()
+		if set {
+			server.Settings[key] = value
+		} else {
+			delete(server.Settings, key)
+		}
+		u.propsMu.Unlock()
+		result.Applied = append(result.Applied, key)
+	}
+
+	if state, _ := u.stateOf(name); restart && state == StateRunning && len(result.PendingRestart) > 0 {
+		if _, err := u.RestartServer(name); err != nil {
+			return nil, fmt.Errorf("restart failed: %w", err)
+		}
+		result.Restarted = true
+		result.PendingRestart = nil
+	}
+	return result, nil
+}
+
+// reloadAllowlist makes a running server pick up allowlist.json.
+func (u *bedrockUC) reloadAllowlist(world string) {
+	if u.liveServer(world) == nil {
+		return
+	}
+	if err := u.SendCommandforAPI(world, "allowlist reload"); err != nil {
+		log.Printf("server %s: allowlist reload failed: %s", world, err)
+	}
+}
+
+// reloadPermissions makes a running server pick up permissions.json.
+func (u *bedrockUC) reloadPermissions(world string) {
+	if u.liveServer(world) == nil {
+		return
+	}
+	if err := u.SendCommandforAPI(world, "permission reload"); err != nil {
+		log.Printf("server %s: permission reload failed: %s", world, err)
+	}
+}
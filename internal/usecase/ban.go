@@ -0,0 +1,444 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"minecrat_go/internal/repository"
+	"minecrat_go/model"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// actions in the audit trail of a ban
+const (
+	BanActionBan       = "ban"
+	BanActionUnban     = "unban"
+	BanActionExpire    = "expire"
+	BanActionKick      = "kick"
+	BanActionAllowlist = "allowlist"
+
+	systemActor = "system"
+)
+
+// allowlistBackup is an allowlist entry a ban took out of a world.
+type allowlistBackup struct {
+	World string        `json:"world"`
+	Entry dto.Allowlist `json:"entry"`
+}
+
+// parseDuration accepts a Go duration or a number of days like "7d".
+func parseDuration(raw string) (time.Duration, error) {
+	raw = strings.TrimSpace(raw)
+	var d time.Duration
+	if days, ok := strings.CutSuffix(raw, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("invalid duration %q", raw)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(raw); err != nil {
+			return 0, fmt.Errorf("invalid duration %q", raw)
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("duration must be positive")
+	}
+	return d, nil
+}
+
+func banActive(ban *model.Ban, at time.Time) bool {
+	return ban.LiftedAt == nil && (ban.ExpiresAt == nil || ban.ExpiresAt.After(at))
+}
+
+func toBanDTO(ban *model.Ban, world string, at time.Time) dto.Ban {
+	resp := dto.Ban{
+		ID:         ban.ID,
+		Xuid:       ban.Xuid,
+		Gamertag:   ban.Gamertag,
+		World:      world,
+		Reason:     ban.Reason,
+		IssuedBy:   ban.IssuedBy,
+		CreatedAt:  ban.CreatedAt,
+		ExpiresAt:  ban.ExpiresAt,
+		LiftedAt:   ban.LiftedAt,
+		LiftedBy:   ban.LiftedBy,
+		LiftReason: ban.LiftReason,
+		Active:     banActive(ban, at),
+	}
+	if world == "" && ban.WorldServer != nil {
+		resp.World = ban.WorldServer.Name
+	}
+	for _, audit := range ban.Audits {
+		resp.Audit = append(resp.Audit, dto.BanAudit{
+			Action: audit.Action,
+			Actor:  audit.Actor,
+			Detail: audit.Detail,
+			At:     audit.At,
+		})
+	}
+	return resp
+}
+
+// banWorlds returns the names of the worlds a ban covers.
+func (u *bedrockUC) banWorlds(ban *model.Ban) ([]string, error) {
+	if ban.WorldServerId != nil {
+		if ban.WorldServer == nil {
+			return nil, nil
+		}
+		return []string{ban.WorldServer.Name}, nil
+	}
+
+	worlds, err := u.bedRepo.GetWorldPorts()
+	if err != nil {
+		return nil, err
+	}
+	names := make([]string, 0, len(worlds))
+	for _, world := range worlds {
+		names = append(names, world.Name)
+	}
+	return names, nil
+}
+
+func (u *bedrockUC) CreateBan(req *dto.BanReq) (*dto.Ban, error) {
+	if hasControl(req.Reason) {
+		// the reason ends up in the kick command
+		return nil, fmt.Errorf("%w: reason must be a single line", utils.ErrInvalidBan)
+	}
+	id := strings.TrimSpace(req.Player)
+	ban := model.Ban{
+		Reason:    strings.TrimSpace(req.Reason),
+		IssuedBy:  req.Actor,
+		CreatedAt: time.Now(),
+	}
+	if player, err := u.resolvePlayer(id); err == nil {
+		ban.Xuid, ban.Gamertag = player.Xuid, player.Gamertag
+	} else if errors.Is(err, utils.ErrPlayerNotFound) && isXuid(id) {
+		// never connected yet, the ban still catches the first join
+		ban.Xuid = id
+	} else {
+		return nil, err
+	}
+	if req.ActorId != 0 {
+		actorId := req.ActorId
+		ban.IssuedById = &actorId
+	}
+
+	if req.World != "" {
+		worlddb, err := u.bedRepo.GetWorldByName(req.World)
+		if err != nil {
+			return nil, fmt.Errorf("server %s not found", req.World)
+		}
+		ban.WorldServerId = &worlddb.ID
+		ban.WorldServer = worlddb
+	}
+	if req.Duration != "" {
+		d, err := parseDuration(req.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", utils.ErrInvalidBan, err)
+		}
+		expires := ban.CreatedAt.Add(d)
+		ban.ExpiresAt = &expires
+	}
+
+	worlds, err := u.banWorlds(&ban)
+	if err != nil {
+		return nil, err
+	}
+
+	backup := u.removeFromAllowlists(worlds, ban.Xuid, ban.Gamertag)
+	if len(backup) > 0 {
+		raw, err := json.Marshal(backup)
+		if err != nil {
+			return nil, err
+		}
+		ban.AllowlistBackup = string(raw)
+	}
+
+	audit := model.BanAudit{
+		Action:  BanActionBan,
+		ActorId: ban.IssuedById,
+		Actor:   req.Actor,
+		Detail:  banDetail(&ban, req.World),
+		At:      ban.CreatedAt,
+	}
+	if err := u.bedRepo.CreateBan(&ban, &audit); err != nil {
+		u.restoreAllowlists(backup)
+		return nil, err
+	}
+	if len(backup) > 0 {
+		u.addBanAudit(ban.ID, BanActionAllowlist, systemActor, "removed from the allowlist of "+backupWorlds(backup))
+	}
+
+	u.enforceBan(&ban, worlds)
+
+	resp := toBanDTO(&ban, req.World, time.Now())
+	return &resp, nil
+}
+
+func banDetail(ban *model.Ban, world string) string {
+	scope := "all worlds"
+	if world != "" {
+		scope = "world " + world
+	}
+	if ban.ExpiresAt == nil {
+		return "permanent ban on " + scope
+	}
+	return fmt.Sprintf("ban on %s until %s", scope, ban.ExpiresAt.Format(time.RFC3339))
+}
+
+func backupWorlds(backup []allowlistBackup) string {
+	worlds := make([]string, 0, len(backup))
+	for _, b := range backup {
+		worlds = append(worlds, b.World)
+	}
+	return strings.Join(worlds, ", ")
+}
+
+func (u *bedrockUC) addBanAudit(banId uint, action, actor, detail string) {
+	audit := model.BanAudit{BanId: banId, Action: action, Actor: actor, Detail: detail, At: time.Now()}
+	if err := u.bedRepo.AddBanAudit(&audit); err != nil {
+		log.Printf("ban %d: save audit failed: %s", banId, err)
+	}
+}
+
+// enforceBan kicks the banned player from every covered world it is on.
+func (u *bedrockUC) enforceBan(ban *model.Ban, worlds []string) {
+	for _, world := range worlds {
+		u.s.RLock()
+		server, ok := u.servers[world]
+		u.s.RUnlock()
+		if !ok {
+			continue
+		}
+
+		server.PlayerMu.Lock()
+		player, online := server.Online[ban.Xuid]
+		server.PlayerMu.Unlock()
+		if online {
+			go u.kickBanned(world, player.Name, ban, "while online")
+		}
+	}
+}
+
+// onBanEvent kicks a banned player as soon as it connects, and once more when
+// it spawns in case the first kick came too early to find it.
+func (u *bedrockUC) onBanEvent(ev ServerEvent) {
+	if ev.Kind != KindPlayerConnected && ev.Kind != KindPlayerSpawned {
+		return
+	}
+	if ev.Xuid == "" {
+		return
+	}
+
+	ban, err := u.bedRepo.GetActiveBan(ev.Xuid, ev.server.Id, time.Now())
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			log.Printf("server %s: ban check of %s failed: %s", ev.World, ev.Player, err)
+		}
+		return
+	}
+
+	when := "on connect"
+	if ev.Kind == KindPlayerSpawned {
+		when = "on spawn"
+	}
+	// the kick goes through the console, which must not wait on this scanner
+	go u.kickBanned(ev.World, ev.Player, ban, when)
+}
+
+func (u *bedrockUC) kickBanned(world, gamertag string, ban *model.Ban, when string) {
+	message := "You are banned from this server"
+	if ban.Reason != "" {
+		message += ": " + ban.Reason
+	}
+	if err := u.SendCommandforAPI(world, kickCommand(gamertag, message)); err != nil {
+		log.Printf("server %s: kick of banned %s failed: %s", world, gamertag, err)
+		return
+	}
+	log.Printf("server %s: kicked banned player %s (ban %d)", world, gamertag, ban.ID)
+	u.addBanAudit(ban.ID, BanActionKick, systemActor, fmt.Sprintf("kicked %s from %s %s", gamertag, world, when))
+}
+
+// LiftBan ends a ban before it expires.
+func (u *bedrockUC) LiftBan(id uint, req *dto.UnbanReq) (*dto.Ban, error) {
+	ban, err := u.bedRepo.GetBan(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %d", utils.ErrBanNotFound, id)
+	}
+	if ban.LiftedAt != nil {
+		return nil, fmt.Errorf("%w: ban %d already ended", utils.ErrInvalidBan, id)
+	}
+
+	now := time.Now()
+	ban.LiftedAt = &now
+	ban.LiftedBy = req.Actor
+	ban.LiftReason = strings.TrimSpace(req.Reason)
+
+	audit := model.BanAudit{Action: BanActionUnban, Actor: req.Actor, Detail: ban.LiftReason, At: now}
+	if req.ActorId != 0 {
+		actorId := req.ActorId
+		audit.ActorId = &actorId
+	}
+	if err := u.bedRepo.LiftBan(ban, &audit); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: ban %d already ended", utils.ErrInvalidBan, id)
+		}
+		return nil, err
+	}
+	u.endBan(ban)
+
+	resp := toBanDTO(ban, "", now)
+	return &resp, nil
+}
+
+// expireBans lifts the temporary bans whose time is up, it runs every
+// minute from the cron runner.
+func (u *bedrockUC) expireBans() {
+	bans, err := u.bedRepo.GetExpiredBans(time.Now())
+	if err != nil {
+		log.Printf("expire bans failed: %s", err)
+		return
+	}
+
+	for i := range bans {
+		ban := &bans[i]
+		ban.LiftedAt = ban.ExpiresAt
+		ban.LiftedBy = systemActor
+		ban.LiftReason = "expired"
+
+		audit := model.BanAudit{Action: BanActionExpire, Actor: systemActor, At: time.Now()}
+		if err := u.bedRepo.LiftBan(ban, &audit); err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("ban %d: expire failed: %s", ban.ID, err)
+			}
+			continue
+		}
+		u.endBan(ban)
+	}
+}
+
+// endBan puts back the allowlist entries a ban removed, unless another ban
+// still keeps the player off that world.
+func (u *bedrockUC) endBan(ban *model.Ban) {
+	if ban.AllowlistBackup == "" {
+		return
+	}
+	var backup []allowlistBackup
+	if err := json.Unmarshal([]byte(ban.AllowlistBackup), &backup); err != nil {
+		log.Printf("ban %d: invalid allowlist backup: %s", ban.ID, err)
+		return
+	}
+
+	restore := backup[:0]
+	for _, b := range backup {
+		worlddb, err := u.bedRepo.GetWorldByName(b.World)
+		if err != nil {
+			continue
+		}
+		if _, err := u.bedRepo.GetActiveBan(ban.Xuid, worlddb.ID, time.Now()); err == nil {
+			continue
+		}
+		restore = append(restore, b)
+	}
+	u.restoreAllowlists(restore)
+	if len(restore) > 0 {
+		u.addBanAudit(ban.ID, BanActionAllowlist, systemActor, "restored to the allowlist of "+backupWorlds(restore))
+	}
+}
+
+func (u *bedrockUC) GetBans(player, world string, activeOnly bool) ([]dto.Ban, error) {
+	filter := repository.BanFilter{ActiveOnly: activeOnly, At: time.Now()}
+	if player != "" {
+		xuid, err := u.playerXuid(player)
+		if err != nil {
+			return nil, err
+		}
+		filter.Xuid = xuid
+	}
+	if world != "" {
+		worlddb, err := u.bedRepo.GetWorldByName(world)
+		if err != nil {
+			return nil, fmt.Errorf("server %s not found", world)
+		}
+		filter.WorldId = worlddb.ID
+	}
+
+	bans, err := u.bedRepo.GetBans(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]dto.Ban, 0, len(bans))
+	for i := range bans {
+		resp = append(resp, toBanDTO(&bans[i], "", filter.At))
+	}
+	return resp, nil
+}
+
+// GetBan returns a ban with its audit trail.
+func (u *bedrockUC) GetBan(id uint) (*dto.Ban, error) {
+	ban, err := u.bedRepo.GetBan(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %d", utils.ErrBanNotFound, id)
+	}
+	resp := toBanDTO(ban, "", time.Now())
+	return &resp, nil
+}
+
+// removeFromAllowlists takes a player off the allowlist of every given world
+// and returns what it removed.
+func (u *bedrockUC) removeFromAllowlists(worlds []string, xuid, gamertag string) []allowlistBackup {
+	var backup []allowlistBackup
+	for _, world := range worlds {
+		var removed []model.AllowlistEntry
+		_, err := u.editAccess(world, allowlistFile, "", func(worldId uint) (bool, error) {
+			var err error
+			removed, err = u.bedRepo.DeleteAllowlistEntries(worldId, xuid, gamertag)
+			return len(removed) > 0, err
+		})
+		if err != nil {
+			log.Printf("server %s: update allowlist failed: %s", world, err)
+			continue
+		}
+		for _, entry := range toAllowlistDTOs(removed) {
+			backup = append(backup, allowlistBackup{World: world, Entry: entry})
+		}
+	}
+	return backup
+}
+
+func (u *bedrockUC) restoreAllowlists(backup []allowlistBackup) {
+	byWorld := make(map[string][]dto.Allowlist)
+	for _, b := range backup {
+		byWorld[b.World] = append(byWorld[b.World], b.Entry)
+	}
+
+	for world, entries := range byWorld {
+		_, err := u.editAccess(world, allowlistFile, "", func(worldId uint) (bool, error) {
+			for _, entry := range entries {
+				err := u.bedRepo.SetAllowlistEntry(&model.AllowlistEntry{
+					WorldServerId:      worldId,
+					Xuid:               entry.Xuid,
+					Name:               entry.Name,
+					IgnoresPlayerLimit: entry.Priority,
+				})
+				if err != nil {
+					return false, err
+				}
+			}
+			return true, nil
+		})
+		if err != nil {
+			log.Printf("server %s: restore allowlist failed: %s", world, err)
+		}
+	}
+}
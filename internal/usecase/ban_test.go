@@ -0,0 +1,52 @@
+package usecase
+
+import (
+	"errors"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"minecrat_go/model"
+	"slices"
+	"testing"
+)
+
+func TestKickCommand(t *testing.T) {
+	tests := []struct {
+		gamertag, message, want string
+	}{
+		{"Steve", "", `kick "Steve"`},
+		{"Steve", "bye", `kick "Steve" bye`},
+		{"Steve", "bye\nstop", `kick "Steve" bye stop`},
+		{"Steve", "bye\r\nop Alex", `kick "Steve" bye  op Alex`},
+		{"Ste\"ve\n", "", `kick "Steve "`},
+	}
+	for _, tt := range tests {
+		if got := kickCommand(tt.gamertag, tt.message); got != tt.want {
+			t.Errorf("kickCommand(%q, %q) = %q, want %q", tt.gamertag, tt.message, got, tt.want)
+		}
+	}
+}
+
+func TestCreateBanRejectsMultilineReason(t *testing.T) {
+	u, _ := newTestUC(t, NewFakeRuntime())
+	_, err := u.CreateBan(&dto.BanReq{Player: "2535400000000001", Reason: "griefing\nstop"})
+	if !errors.Is(err, utils.ErrInvalidBan) {
+		t.Fatalf("err = %v, want ErrInvalidBan", err)
+	}
+}
+
+func TestKickBannedCannotInjectCommands(t *testing.T) {
+	fake := NewFakeRuntime()
+	u, _ := newTestUC(t, fake, testWorld("alpha", 1))
+	startWorld(t, u, "alpha")
+	joinPlayer(t, u, fake, "alpha", "Steve", "2535400000000001")
+
+	u.kickBanned("alpha", "Steve", &model.Ban{ID: 1, Reason: "griefing\nstop"}, "on connect")
+	eventually(t, "Steve to be kicked", func() bool {
+		players, _ := u.GetOnlinePlayers("alpha")
+		return len(players) == 0
+	})
+
+	if commands := sentCommands(t, u, fake, "alpha"); slices.Contains(commands, "stop") {
+		t.Errorf("console got %q, the reason ran as a command", commands)
+	}
+}
@@ -2,80 +2,329 @@ package usecase
 
 import (
 	"bufio"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"log"
 	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
 	"minecrat_go/internal/repository"
+	"minecrat_go/model"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
-	"strings"
 	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/robfig/cron/v3"
 )
 
 type BedrockServer struct {
-	Cmd    *exec.Cmd
-	Writer *bufio.Writer
-	Port   int
-	Name   string
-	Id     uint
-	Logs   []string
-	LogMu  sync.RWMutex
+	Proc ServerProcess
+	// Adopted servers were left behind by a previous manager process and
+	// have no Writer.
+	Adopted bool
+	Cgroup  string
+	Writer  *bufio.Writer
+	WriteMu sync.Mutex
+	// CmdMu serializes console commands so captured output is not mixed.
+	CmdMu sync.Mutex
+	Port  int
+	Name  string
+	Id    uint
+	Logs  []string
+	LogMu sync.RWMutex
+	// capture receives every scanned line while RunCommand waits for output.
+	capture chan ServerEvent
+
+	StartedAt time.Time
+
+	// done is closed once the supervisor has recorded the exit, waitErr and
+	// exitStatus hold the result of Proc.Wait.
+	done       chan struct{}
+	waitErr    error
+	exitStatus int
+	scanDone   chan struct{}
+
+	// ready is closed once the server printed "Server started." or failed to
+	// come up, readyErr tells which. Version and the ports are parsed from
+	// the startup banner and guarded by LogMu.
+	ready     chan struct{}
+	readyOnce sync.Once
+	readyErr  error
+	Version   string
+	PortV4    int
+	PortV6    int
+
+	// Settings holds the server.properties values the process runs with,
+	// guarded by propsMu of bedrockUC.
+	Settings map[string]string
+
+	// Online is the roster of connected players by xuid.
+	PlayerMu  sync.Mutex
+	Online    map[string]onlinePlayer
+	idleTimer *time.Timer
+}
+
+func (s *BedrockServer) markReady(err error) {
+	s.readyOnce.Do(func() {
+		s.readyErr = err
+		close(s.ready)
+	})
+}
+
+func (s *BedrockServer) isReady() bool {
+	select {
+	case <-s.ready:
+		return s.readyErr == nil
+	default:
+		return false
+	}
+}
+
+func (s *BedrockServer) banner() (version string, portV4, portV6 int) {
+	s.LogMu.RLock()
+	defer s.LogMu.RUnlock()
+	return s.Version, s.PortV4, s.PortV6
+}
+
+func (s *BedrockServer) writeLine(line string) error {
+	s.WriteMu.Lock()
+	defer s.WriteMu.Unlock()
+
+	if s.Adopted {
+		return fmt.Errorf("server %s was adopted after a manager restart and has no console", s.Name)
+	}
+	if s.Writer == nil {
+		return fmt.Errorf("writer not initialized for server %s", s.Name)
+	}
+	if _, err := s.Writer.WriteString(line + "\n"); err != nil {
+		return err
+	}
+	return s.Writer.Flush()
+}
+
+// waitExit reports whether the process exited within timeout.
+func (s *BedrockServer) waitExit(timeout time.Duration) bool {
+	t := time.NewTimer(timeout)
+	defer t.Stop()
+
+	select {
+	case <-s.done:
+		return true
+	case <-t.C:
+		return false
+	}
+}
+
+func (s *BedrockServer) tailLogs(n int) []string {
+	s.LogMu.RLock()
+	defer s.LogMu.RUnlock()
+
+	if len(s.Logs) < n {
+		n = len(s.Logs)
+	}
+	return append([]string(nil), s.Logs[len(s.Logs)-n:]...)
+}
+
+func (s *BedrockServer) signal(sig os.Signal) error {
+	return s.Proc.Signal(sig)
+}
+
+func (s *BedrockServer) exitCode() int {
+	select {
+	case <-s.done:
+		return s.exitStatus
+	default:
+		return -1
+	}
 }
 
 type BedrockUC interface {
 	//world
 	CreateServer(req *dto.ServerParams) error
-	StopServer(name string) error
-	StartServer(req *dto.StartServerReq) error
+	StopServer(name string, timeout time.Duration) (*dto.StopServerResult, error)
+	StartServer(req *dto.StartServerReq) (*dto.StartServerResult, error)
 	DeleteWorld(user uint, name string) error
-	EditWorld(req *dto.ServerParams, idWorld uint, nameOld string) error
+	EditWorld(req *dto.ServerParams, idWorld uint, nameOld string) (*dto.ApplyResult, error)
 	GetWorlds() ([]dto.GetWorlds, error)
 	GetWorldAndPlayers(name string) (*dto.GetWorldAndPlayers, error)
 	SendCommandforAPI(name string, command string) error
-	CreatePriority(req *dto.Allowlist, worldName string) error
-	DeletePriority(xuid, worldName string) error
+	RunCommand(name, command string, timeout time.Duration) (*dto.CommandResult, error)
+	CreatePriority(req *dto.Allowlist, worldName, ifMatch string) (string, error)
+	DeletePriority(xuid, worldName, ifMatch string) (string, error)
 
 	//player
-	KickPlayer(name string, playerName string) error
-	BanPlayer(name string, playerName string) error
-	CreateOrUpdatePermissions(req *dto.PermissionPlayer, worldName string) error
-	DeletePermission(xuid, worldName string) error
-	GetPermissionPlayer(name string) ([]dto.PermissionPlayer, error)
+	KickPlayer(name string, req *dto.ModerationReq) (*dto.ModerationAction, error)
+	WarnPlayer(name string, req *dto.ModerationReq) (*dto.ModerationAction, error)
+	MutePlayer(name string, req *dto.ModerationReq) (*dto.ModerationAction, error)
+	UnmutePlayer(name string, req *dto.ModerationReq) (*dto.ModerationAction, error)
+	GetModerationHistory(player, world string) (*dto.ModerationHistory, error)
+	CreateOrUpdatePermissions(req *dto.PermissionPlayer, worldName, ifMatch string) (string, error)
+	DeletePermission(xuid, worldName, ifMatch string) (string, error)
+	GetPermissionPlayer(name string) ([]dto.PermissionPlayer, string, error)
 	GetServerLogs(name string) ([]string, error)
-	GetPriority(name string) ([]dto.Allowlist, error)
+	GetOnlinePlayers(name string) ([]dto.OnlinePlayer, error)
+	GetPlayerSessions(name, xuid string, limit, offset int) (*dto.SessionPage, error)
+	GetPlaytime(name string) ([]dto.Playtime, error)
+	GetPlayers(query string, limit, offset int) (*dto.PlayerPage, error)
+	GetPlayer(id string) (*dto.PlayerProfile, error)
+
+	//ban
+	CreateBan(req *dto.BanReq) (*dto.Ban, error)
+	LiftBan(id uint, req *dto.UnbanReq) (*dto.Ban, error)
+	GetBans(player, world string, activeOnly bool) ([]dto.Ban, error)
+	GetBan(id uint) (*dto.Ban, error)
+	GetPropertySchema() []dto.PropertySpec
+	GetProperties(name string) (*dto.ServerProperties, error)
+	PatchProperties(name string, patch map[string]json.RawMessage, restart bool) (*dto.ServerProperties, error)
+	GetLogHistory(name string, q dto.LogQuery) (*dto.LogPage, error)
+	GetLogFiles(name string) ([]dto.LogFile, error)
+	LogFilePath(name, file string) (string, error)
+	SubscribeConsole(name string, history int) (<-chan string, func(), error)
+	SubscribeEvents(name string, lastID uint64) (<-chan dto.Event, func(), error)
+	GetPriority(name string) ([]dto.Allowlist, string, error)
+	GetAllowlist(name string) (*dto.AllowlistState, string, error)
+	SetAllowlistEnabled(name string, enabled bool) (*dto.ApplyResult, error)
+	RemoveFromAllowlist(name, player, ifMatch string) (string, error)
+	ReplaceAllowlist(name string, entries []dto.Allowlist, ifMatch string) (*dto.AllowlistImport, string, error)
+	CopyAllowlist(name, from, ifMatch string) (*dto.AllowlistImport, string, error)
+	GetAccessDrift(name string) (*dto.AccessDrift, error)
+	ReconcileAccess(name, source string) (*dto.AccessDrift, error)
+	GetServerStatus(name string) (*dto.ServerStatus, error)
+	GetResourceUsage(name string) (*dto.ResourceUsage, error)
+	Reconcile() error
+	RestartServer(name string) (*dto.StartServerResult, error)
+
+	//schedule
+	LoadSchedules() error
+	GetSchedules(world string) ([]dto.Schedule, error)
+	CreateSchedule(world string, req *dto.ScheduleReq) (*dto.Schedule, error)
+	UpdateSchedule(world string, id uint, req *dto.ScheduleReq) (*dto.Schedule, error)
+	DeleteSchedule(world string, id uint) error
+	StartAutostart(ctx context.Context)
+	StopAll()
 
 	//non import
-	handleLogLine(line string, worldId uint)
 	copyDir(src, dst string) error
 	copyFile(src, dst string) error
 	modifyProperties(req *dto.ServerParams, worldname string) error
 }
 
 type bedrockUC struct {
-	servers map[string]*BedrockServer
-	s       sync.RWMutex
-	bedRepo repository.BedrockRepo
+	servers     map[string]*BedrockServer
+	supervisors map[string]*supervisor
+	s           sync.RWMutex
+	bedRepo     repository.BedrockRepo
+	runtime     ServerRuntime
+
+	startTimeout   time.Duration
+	commandTimeout time.Duration
+	commandQuiet   time.Duration
+	commandGrace   time.Duration
+	stopTimeout    time.Duration
+	termTimeout    time.Duration
+	backoffBase    time.Duration
+	backoffMax     time.Duration
+	stableDuration time.Duration
+	orphanPolicy   string
+	cgroupRoot     string
+	// logMaxSize and logRotateEvery rotate logs/server.log, rotated files
+	// older than logRetention are removed.
+	logMaxSize     int64
+	logRotateEvery time.Duration
+	logRetention   time.Duration
+	// autostartParallel bounds how many worlds of one start order boot at once.
+	autostartParallel int
+	// closing is set by StopAll, no world may start after it. shutdown is
+	// closed at the same time to wake up scheduled countdowns.
+	closing  atomic.Bool
+	shutdown chan struct{}
+
+	events    *eventStream
+	bus       serverEventBus
+	consoles  map[string]*consoleHub
+	consoleMu sync.Mutex
+
+	propsMu sync.Mutex
+	// files guards permissions.json and allowlist.json of every world.
+	files fileStore
+
+	cron       *cron.Cron
+	schedules  map[uint]scheduleEntry
+	scheduleMu sync.Mutex
+
+	// portMu serializes port allocation between concurrent creates and edits.
+	portMu sync.Mutex
+	ports  portRange
+
+	hooks launchHooks
 }
 
-func NewBedrockUC(bedRepo repository.BedrockRepo) BedrockUC {
-	return &bedrockUC{
-		servers: make(map[string]*BedrockServer),
-		s:       sync.RWMutex{},
-		bedRepo: bedRepo,
-	}
+// launchHooks are the side effects of a launch on the host, tests replace
+// them so no real port is probed and no cgroup or log file is created.
+type launchHooks struct {
+	checkPorts    func(name string, ports ...int) error
+	portHolder    func(port int) string
+	prepareCgroup func(name string, limits cgroupLimits) (string, error)
+	openLog       func(name string) (*worldLog, error)
+}
 
+func NewBedrockUC(bedRepo repository.BedrockRepo, runtime ServerRuntime) BedrockUC {
+	u := &bedrockUC{
+		servers:           make(map[string]*BedrockServer),
+		supervisors:       make(map[string]*supervisor),
+		s:                 sync.RWMutex{},
+		bedRepo:           bedRepo,
+		runtime:           runtime,
+		startTimeout:      utils.GetEnvDuration("BEDROCK_START_TIMEOUT", 2*time.Minute),
+		commandTimeout:    utils.GetEnvDuration("BEDROCK_COMMAND_TIMEOUT", 5*time.Second),
+		commandQuiet:      utils.GetEnvDuration("BEDROCK_COMMAND_QUIET", 300*time.Millisecond),
+		commandGrace:      utils.GetEnvDuration("BEDROCK_COMMAND_GRACE", time.Second),
+		stopTimeout:       utils.GetEnvDuration("BEDROCK_STOP_TIMEOUT", 30*time.Second),
+		termTimeout:       utils.GetEnvDuration("BEDROCK_TERM_TIMEOUT", 10*time.Second),
+		backoffBase:       utils.GetEnvDuration("BEDROCK_RESTART_BACKOFF", 5*time.Second),
+		backoffMax:        utils.GetEnvDuration("BEDROCK_RESTART_BACKOFF_MAX", 5*time.Minute),
+		stableDuration:    utils.GetEnvDuration("BEDROCK_RESTART_RESET_AFTER", 10*time.Minute),
+		orphanPolicy:      os.Getenv("BEDROCK_ORPHAN_POLICY"),
+		cgroupRoot:        utils.GetEnv("BEDROCK_CGROUP_ROOT", "/sys/fs/cgroup/bedrock.slice"),
+		logMaxSize:        int64(utils.GetEnvInt("BEDROCK_LOG_MAX_SIZE_MB", 10)) << 20,
+		logRotateEvery:    utils.GetEnvDuration("BEDROCK_LOG_ROTATE_EVERY", 24*time.Hour),
+		logRetention:      utils.GetEnvDuration("BEDROCK_LOG_RETENTION", 14*24*time.Hour),
+		ports:             parsePortRange(os.Getenv("BEDROCK_PORT_RANGE")),
+		autostartParallel: max(utils.GetEnvInt("BEDROCK_AUTOSTART_PARALLEL", 2), 1),
+		cron:              cron.New(cron.WithChain(cron.SkipIfStillRunning(cron.DiscardLogger))),
+		schedules:         make(map[uint]scheduleEntry),
+		consoles:          make(map[string]*consoleHub),
+		events:            newEventStream(utils.GetEnvInt("BEDROCK_EVENT_BUFFER", 4096)),
+		shutdown:          make(chan struct{}),
+	}
+	u.hooks = launchHooks{
+		checkPorts:    u.checkPorts,
+		portHolder:    u.portHolder,
+		prepareCgroup: u.prepareCgroup,
+		openLog:       u.openWorldLog,
+	}
+	u.subscribeServerEvents()
+	return u
 }
 
 func (u *bedrockUC) CreateServer(req *dto.ServerParams) error {
 	src := "config/world_template/"
 	dst := filepath.Join("data/servers", req.Name)
 
+	u.portMu.Lock()
+	if err := u.assignPorts(req, 0, true); err != nil {
+		u.portMu.Unlock()
+		return err
+	}
 	worlddb, err := u.bedRepo.CreateWorld(req)
+	u.portMu.Unlock()
 	if err != nil {
 		return err
 	}
@@ -91,124 +340,325 @@ func (u *bedrockUC) CreateServer(req *dto.ServerParams) error {
 		return fmt.Errorf("modify properties failed: %w", err)
 	}
 
-	log.Printf("Server %s created on port %d", req.Name, req.Port)
+	world, err := u.bedRepo.GetWorldByName(worlddb.Name)
+	if err != nil {
+		return err
+	}
+	if err := u.writeAccessFiles(world.Name, world.ID); err != nil {
+		return err
+	}
+
+	log.Printf("Server %s created on port %d/%d", req.Name, req.Port, req.PortV6)
 	return nil
 }
 
-func (u *bedrockUC) StartServer(req *dto.StartServerReq) error {
-	dst := filepath.Join("data/servers", req.Name)
-
-	cmd := exec.Command("./bedrock_server")
-	cmd.Dir = dst
-
-	stdin, err := cmd.StdinPipe()
+func (u *bedrockUC) StartServer(req *dto.StartServerReq) (*dto.StartServerResult, error) {
+	begin := time.Now()
+	sup, err := u.resetSupervisor(req)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	stdout, err := cmd.StdoutPipe()
+	server, err := u.launch(req.Name, sup)
 	if err != nil {
-		stdin.Close()
-		return err
+		sup.mu.Lock()
+		sup.lastExit = &dto.ExitInfo{Code: -1, Error: err.Error(), At: time.Now()}
+		sup.transition(StateCrashed)
+		sup.mu.Unlock()
+		return nil, err
 	}
 
-	stderr, err := cmd.StderrPipe()
-	if err != nil {
-		stdin.Close()
-		stdout.Close()
-		return err
+	log.Printf("Server %s (port: %d) is starting", req.Name, req.Port)
+	if !req.Wait {
+		return u.startResult(req.Name, server, begin), nil
+	}
+
+	timeout := u.startTimeout
+	if req.Timeout > 0 {
+		timeout = time.Duration(req.Timeout) * time.Second
 	}
+	t := time.NewTimer(timeout)
+	defer t.Stop()
 
-	combined := io.MultiReader(stdout, stderr)
-	reader := io.TeeReader(combined, os.Stdout)
+	select {
+	case <-server.ready:
+	case <-t.C:
+		return nil, fmt.Errorf("%w: server %s is still starting after %s", utils.ErrStartTimeout, req.Name, timeout)
+	}
 
-	if err := cmd.Start(); err != nil {
-		stdin.Close()
-		stdout.Close()
-		stderr.Close()
-		return err
+	if server.readyErr != nil {
+		if errors.Is(server.readyErr, utils.ErrPortInUse) {
+			// retrying on an occupied port would only crash loop
+			if _, err := u.StopServer(req.Name, u.termTimeout); err != nil {
+				log.Printf("server %s: stop after failed start: %s", req.Name, err)
+			}
+		}
+		return nil, server.readyErr
+	}
+
+	return u.startResult(req.Name, server, begin), nil
+}
+
+func (u *bedrockUC) startResult(name string, server *BedrockServer, begin time.Time) *dto.StartServerResult {
+	state, _ := u.stateOf(name)
+	version, portV4, portV6 := server.banner()
+	return &dto.StartServerResult{
+		Name:     name,
+		State:    string(state),
+		Ready:    server.isReady(),
+		Version:  version,
+		PortV4:   portV4,
+		PortV6:   portV6,
+		Duration: time.Since(begin).Round(time.Millisecond).String(),
+	}
+}
+
+// launch spawns bedrock_server for the world and hands it to its supervisor.
+func (u *bedrockUC) launch(name string, sup *supervisor) (*BedrockServer, error) {
+	req, runtime, limits := sup.startReq()
+	dst := filepath.Join("data/servers", name)
+
+	var cgroup string
+	if runtime != RuntimeOCI {
+		path, err := u.hooks.prepareCgroup(name, limits)
+		if err != nil {
+			return nil, err
+		}
+		cgroup = path
+	}
+
+	if err := u.hooks.checkPorts(name, req.Port, req.PortV6); err != nil {
+		removeCgroup(cgroup)
+		return nil, err
+	}
+	u.syncAccessFiles(name, req.WorldId)
+
+	proc, err := u.runtime.Start(RuntimeSpec{
+		Name:    name,
+		Dir:     dst,
+		WorldId: req.WorldId,
+		Port:    req.Port,
+		PortV6:  req.PortV6,
+		Runtime: runtime,
+		Cgroup:  cgroup,
+		Limits:  limits,
+	})
+	if err != nil {
+		removeCgroup(cgroup)
+		return nil, err
 	}
 
 	server := &BedrockServer{
-		Cmd:    cmd,
-		Writer: bufio.NewWriter(stdin),
-		Port:   req.Port,
-		Name:   dst,
-		Id:     req.WorldId,
-		Logs:   make([]string, 0, 1001),
+		Proc:      proc,
+		Cgroup:    cgroup,
+		Port:      req.Port,
+		Name:      dst,
+		Id:        req.WorldId,
+		Logs:      make([]string, 0, 1001),
+		StartedAt: time.Now(),
+		done:      make(chan struct{}),
+		scanDone:  make(chan struct{}),
+		ready:     make(chan struct{}),
+		Online:    make(map[string]onlinePlayer),
+	}
+
+	if stdin := proc.Stdin(); stdin != nil {
+		server.Writer = bufio.NewWriter(stdin)
 	}
+	u.snapshotSettings(name, server)
 
 	u.s.Lock()
-	u.servers[req.Name] = server
+	u.servers[name] = server
 	u.s.Unlock()
 
-	go func() {
-		defer func() {
-			stdin.Close()
-			stdout.Close()
-			stderr.Close()
-		}()
+	u.saveProcState(name, server, StateStarting)
+	go u.supervise(name, server, sup)
+	go u.scanOutput(name, server, proc.Output())
 
-		scanner := bufio.NewScanner(reader)
-		for scanner.Scan() {
-			line := scanner.Text()
+	return server, nil
+}
 
-			u.handleLogLine(line, req.WorldId)
+// scanOutput reads the output of a server until the process exits.
+func (u *bedrockUC) scanOutput(name string, server *BedrockServer, output io.Reader) {
+	defer close(server.scanDone)
+	if output == nil {
+		return
+	}
 
-			server.LogMu.Lock()
-			if len(server.Logs) > 1000 {
-				server.Logs = server.Logs[1:]
-			}
-			server.Logs = append(server.Logs, line)
-			server.LogMu.Unlock()
+	wlog, err := u.hooks.openLog(name)
+	if err != nil {
+		log.Printf("server %s: open log file failed: %s", name, err)
+	} else if wlog != nil {
+		defer wlog.close()
+	}
+
+	scanner := bufio.NewScanner(output)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		now := time.Now()
+		if wlog != nil {
+			wlog.write(now, line)
 		}
-	}()
 
-	log.Printf("Server %s (port: %d) is online", req.Name, req.Port)
-	return nil
+		ev := parseServerLine(name, line, now)
+		ev.server = server
+		u.bus.publish(ev)
+	}
 }
 
-func (u *bedrockUC) handleLogLine(line string, worldId uint) {
+// subscribeServerEvents wires the features that react to server output to
+// the event bus. The order matters: readiness and players are updated before
+// the line reaches viewers and command captures.
+func (u *bedrockUC) subscribeServerEvents() {
+	u.bus.subscribe(u.onStartupEvent)
+	u.bus.subscribe(u.onPlayerEvent)
+	u.bus.subscribe(u.onBanEvent)
+	u.bus.subscribe(u.onModerationEvent)
+	u.bus.subscribe(u.recordOutput)
+}
 
-	if strings.Contains(line, "Player connected:") {
-		log.Println("add player")
-		parts := strings.Split(line, "Player connected:")
-		if len(parts) < 2 {
-			return
+// onStartupEvent picks the version and the bound ports from the startup
+// banner, fails the start when bedrock_server cannot bind its port and marks
+// the server running once it is up.
+func (u *bedrockUC) onStartupEvent(ev ServerEvent) {
+	server := ev.server
+
+	switch ev.Kind {
+	case KindVersion:
+		server.LogMu.Lock()
+		server.Version = ev.Version
+		server.LogMu.Unlock()
+	case KindPortBound:
+		server.LogMu.Lock()
+		if ev.IPVersion == 4 {
+			server.PortV4 = ev.Port
+		} else {
+			server.PortV6 = ev.Port
 		}
-		data := strings.TrimSpace(parts[1])
-		split := strings.Split(data, ", xuid: ")
-		if len(split) < 2 {
+		server.LogMu.Unlock()
+	case KindPortOccupied:
+		if server.isReady() {
 			return
 		}
+		log.Printf("server %s: port %d is occupied", ev.World, server.Port)
+		server.markReady(fmt.Errorf("%w: server %s cannot bind port %d", utils.ErrPortInUse, ev.World, server.Port))
+	case KindStarted:
+		u.s.RLock()
+		sup, ok := u.supervisors[ev.World]
+		u.s.RUnlock()
+		if ok && sup.setState(StateRunning) {
+			log.Printf("server %s is running", ev.World)
+			u.saveProcState(ev.World, server, StateRunning)
+			u.armIdle(ev.World, server)
+		}
+		server.markReady(nil)
+	}
+}
 
-		xuid := strings.TrimSpace(split[1])
-		err := u.bedRepo.EnsurePlayerExists(xuid, worldId)
-		if err != nil {
-			return
+func (u *bedrockUC) onPlayerEvent(ev ServerEvent) {
+	switch ev.Kind {
+	case KindPlayerConnected:
+		if prev, ok := u.playerJoined(ev.World, ev.server, ev.Xuid, ev.Player, ev.Time); ok {
+			// the disconnect of the previous stay was never printed
+			u.closeSession(ev.World, prev, ev.Time, SessionEndRejoin)
 		}
-		return
+		u.openSession(ev)
+		u.recordPlayer(ev)
+		if err := u.bedRepo.EnsurePlayerExists(ev.Xuid, ev.server.Id); err != nil {
+			log.Printf("server %s: save player %s failed: %s", ev.World, ev.Player, err)
+		}
+	case KindPlayerDisconnected:
+		if player, ok := u.playerLeft(ev.World, ev.server, ev.Xuid); ok {
+			u.closeSession(ev.World, player, ev.Time, SessionEndDisconnect)
+		}
+	}
+}
 
+// recordOutput keeps the recent lines of a server and hands every line to
+// the console viewers, the event stream and a waiting RunCommand.
+func (u *bedrockUC) recordOutput(ev ServerEvent) {
+	server := ev.server
+	hub := u.consoleHub(ev.World)
+
+	server.LogMu.Lock()
+	defer server.LogMu.Unlock()
+
+	if len(server.Logs) > 1000 {
+		server.Logs = server.Logs[1:]
+	}
+	server.Logs = append(server.Logs, ev.Raw)
+	hub.publish(ev.World, ev.Raw)
+	u.events.publish(ev.World, dto.EventLog, dto.LogEvent{Line: ev.Raw, Level: ev.Level, Kind: string(ev.Kind)})
+	if server.capture != nil {
+		select {
+		case server.capture <- ev:
+		default:
+		}
 	}
 }
 
-func (u *bedrockUC) StopServer(name string) error {
+// StopServer asks the server to save and quit through its console, then
+// escalates to SIGTERM and SIGKILL if it does not exit in time. The map
+// entry is removed by the supervisor once Cmd.Wait has returned.
+func (u *bedrockUC) StopServer(name string, timeout time.Duration) (*dto.StopServerResult, error) {
+	u.s.RLock()
 	server, ok := u.servers[name]
-	if !ok {
-		return fmt.Errorf("server not found in map")
+	sup, supervised := u.supervisors[name]
+	u.s.RUnlock()
+	if !supervised {
+		return nil, stateError(name, StateStopped, "stop")
 	}
 
-	err := server.Cmd.Process.Kill()
+	// from here on an exit is expected and must not trigger a restart
+	cancelled, err := sup.beginStop(name)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	if cancelled || !ok {
+		return &dto.StopServerResult{Name: name, Method: dto.StopMethodCancelRestart, ExitCode: -1}, nil
 	}
 
-	u.s.Lock()
-	delete(u.servers, name)
-	u.s.Unlock()
+	if timeout <= 0 {
+		timeout = u.stopTimeout
+	}
 
-	log.Printf("server %s is stop", name)
-	return nil
+	started := time.Now()
+	method := dto.StopMethodCommand
+
+	if err := server.writeLine("stop"); err != nil {
+		log.Printf("server %s: send stop failed: %s", name, err)
+		method = dto.StopMethodTerm
+	} else if !server.waitExit(timeout) {
+		log.Printf("server %s did not stop within %s", name, timeout)
+		method = dto.StopMethodTerm
+	}
+
+	if method == dto.StopMethodTerm {
+		if err := server.signal(syscall.SIGTERM); err != nil {
+			log.Printf("server %s: sigterm failed: %s", name, err)
+		}
+		termTimeout := u.termTimeout
+		if server.Adopted && timeout > termTimeout {
+			// SIGTERM is the only graceful path an adopted server has
+			termTimeout = timeout
+		}
+		if !server.waitExit(termTimeout) {
+			method = dto.StopMethodKill
+			if err := server.signal(os.Kill); err != nil {
+				log.Printf("server %s: sigkill failed: %s", name, err)
+			}
+			<-server.done
+		}
+	}
+
+	log.Printf("server %s is stop (%s)", name, method)
+	return &dto.StopServerResult{
+		Name:     name,
+		Method:   method,
+		ExitCode: server.exitCode(),
+		Duration: time.Since(started).Round(time.Millisecond).String(),
+	}, nil
 }
 
 func (u *bedrockUC) DeleteWorld(user uint, name string) error {
@@ -220,13 +670,52 @@ func (u *bedrockUC) DeleteWorld(user uint, name string) error {
 	if err := u.bedRepo.DeleteWorld(user, name); err != nil {
 		return err
 	}
+	u.unregisterWorldSchedules(name)
 
 	fmt.Printf("%v menghapus server bernama %s", user, name)
 	return nil
 }
 
-func (u *bedrockUC) EditWorld(req *dto.ServerParams, idWorld uint, nameOld string) error {
-	if err := u.modifyProperties(req, nameOld); err != nil {
+// EditWorld saves the world settings. A running world applies the ones the
+// console can change right away, the rest are pending until a restart, which
+// req.Restart does right away.
+func (u *bedrockUC) EditWorld(req *dto.ServerParams, idWorld uint, nameOld string) (*dto.ApplyResult, error) {
+	gamerules := make(map[string]string, len(req.Gamerules))
+	for rule, v := range req.Gamerules {
+		value, err := gameruleValue(rule, v)
+		if err != nil {
+			return nil, err
+		}
+		gamerules[rule] = value
+	}
+	if len(gamerules) > 0 && u.liveServer(nameOld) == nil {
+		return nil, fmt.Errorf("%w: gamerules can only be changed on a running world with a console", utils.ErrInvalidState)
+	}
+
+	if err := u.editWorld(req, idWorld, nameOld); err != nil {
+		return nil, err
+	}
+	return u.applySettings(nameOld, gamerules, req.Restart)
+}
+
+func (u *bedrockUC) editWorld(req *dto.ServerParams, idWorld uint, nameOld string) error {
+	u.portMu.Lock()
+	defer u.portMu.Unlock()
+
+	if req.Port != 0 || req.PortV6 != 0 {
+		if err := u.assignPorts(req, idWorld, false); err != nil {
+			return err
+		}
+	}
+
+	props := *req
+	if props.Port != 0 && props.PortV6 == 0 {
+		// worlds created before port_v6 existed share one port number
+		if world, err := u.bedRepo.GetWorldByName(nameOld); err == nil && world.PortV6 == 0 {
+			props.PortV6 = props.Port
+		}
+	}
+	if err := u.modifyProperties(&props, nameOld); err != nil {
 		return err
 	}
 	if err := u.bedRepo.EditWorld(req, idWorld); err != nil {
@@ -274,68 +763,53 @@ func (u *bedrockUC) copyFile(src, dst string) error {
 	return err
 }
 
+// modifyProperties writes the world settings into server.properties, zero
+// fields are left as they are.
 func (u *bedrockUC) modifyProperties(req *dto.ServerParams, worldname string) error {
-	dst := filepath.Join("data/servers", worldname, "server.properties")
-	input, err := os.ReadFile(dst)
-	if err != nil {
-		return err
-	}
-
-	content := string(input)
-	lines := strings.Split(content, "\n")
-
-	for i, line := range lines {
-		//name
-		if req.Name != "" && strings.HasPrefix(line, "server-name=") {
-			lines[i] = "server-name=" + req.Name
-		}
-
-		//name
-		if req.Name != "" && strings.HasPrefix(line, "level-name=") {
-			lines[i] = "level-name=" + req.Name
+	return u.editProperties(worldname, func(p *properties) error {
+		if req.Name != "" {
+			p.set("server-name", req.Name)
+			p.set("level-name", req.Name)
 		}
-		//game mode
-		if req.GameMode != "" && strings.HasPrefix(line, "gamemode=") {
-			lines[i] = "gamemode=" + req.GameMode
+		if req.GameMode != "" {
+			p.set("gamemode", req.GameMode)
 		}
-		//difficult
-		if req.Difficult != "" && strings.HasPrefix(line, "difficulty=") {
-			lines[i] = "difficulty=" + req.Difficult
+		if req.Difficult != "" {
+			p.set("difficulty", req.Difficult)
 		}
-		//max player
-		if req.MaxPlayer != 0 && strings.HasPrefix(line, "max-players=") {
-			lines[i] = "max-players=" + strconv.Itoa(req.MaxPlayer)
+		if req.MaxPlayer != 0 {
+			p.set("max-players", strconv.Itoa(req.MaxPlayer))
 		}
-		//allow cheats
-		if strings.HasPrefix(line, "allow-cheats=") {
-			lines[i] = "allow-cheats=" + strconv.FormatBool(req.AllowCheat)
+		if req.AllowCheat != nil {
+			p.set("allow-cheats", strconv.FormatBool(*req.AllowCheat))
 		}
-		//seed
-		if req.SeedWorld != "" && strings.HasPrefix(line, "level-seed=") {
-			lines[i] = "level-seed=" + req.SeedWorld
+		if req.SeedWorld != "" {
+			p.set("level-seed", req.SeedWorld)
 		}
-		//permission player
-		if req.DefaultPermissionPlayer != "" && strings.HasPrefix(line, "default-player-permission-level=") {
-			lines[i] = "default-player-permission-level=" + req.DefaultPermissionPlayer
+		if req.DefaultPermissionPlayer != "" {
+			p.set("default-player-permission-level", req.DefaultPermissionPlayer)
 		}
-		//view distance
-		if req.ViewDistance != 0 && strings.HasPrefix(line, "view-distance=") {
-			lines[i] = "view-distance=" + strconv.Itoa(req.ViewDistance)
+		if req.ViewDistance != 0 {
+			p.set("view-distance", strconv.Itoa(req.ViewDistance))
 		}
-		//port
-		if req.Port != 0 && strings.HasPrefix(line, "server-port=") {
-			lines[i] = "server-port=" + strconv.Itoa(req.Port)
+		if req.Port != 0 {
+			p.set("server-port", strconv.Itoa(req.Port))
 		}
-		if req.Port != 0 && strings.HasPrefix(line, "server-portv6=") {
-			lines[i] = "server-portv6=" + strconv.Itoa(req.Port)
+		if req.PortV6 != 0 {
+			p.set("server-portv6", strconv.Itoa(req.PortV6))
 		}
-	}
-
-	output := strings.Join(lines, "\n")
-	return os.WriteFile(dst, []byte(output), 0644)
+		return nil
+	})
 }
 
 func (u *bedrockUC) SendCommandforAPI(name string, command string) error {
+	if hasControl(command) {
+		return fmt.Errorf("%w: command must be a single line", utils.ErrInvalidCommand)
+	}
+	if err := u.requireState(name, "send command to", StateRunning); err != nil {
+		return err
+	}
+
 	u.s.RLock()
 	server, ok := u.servers[name]
 	u.s.RUnlock()
@@ -343,218 +817,134 @@ func (u *bedrockUC) SendCommandforAPI(name string, command string) error {
 		return fmt.Errorf("server %s not found", name)
 	}
 
-	if server.Writer == nil {
-		return fmt.Errorf("writer not initialized for server %s", name)
-	}
+	// wait for a running RunCommand so its capture gets only its own output
+	server.CmdMu.Lock()
+	defer server.CmdMu.Unlock()
+	return server.writeLine(command)
+}
 
-	_, err := server.Writer.WriteString(command + "\n")
+func (u *bedrockUC) CreateOrUpdatePermissions(req *dto.PermissionPlayer, worldName, ifMatch string) (string, error) {
+	xuid, err := u.playerXuid(req.Xuid)
 	if err != nil {
-		return err
+		return "", err
 	}
-	return server.Writer.Flush()
-}
+	req.Xuid = xuid
 
-func (u *bedrockUC) KickPlayer(name string, playerName string) error {
-	kick := fmt.Sprintf(`kick "%s"`, playerName)
-	if err := u.SendCommandforAPI(name, kick); err != nil {
-		return err
-	}
-	return nil
-}
+	return u.editAccess(worldName, permissionsFile, ifMatch, func(worldId uint) (bool, error) {
+		return true, u.bedRepo.SetPermission(worldId, req.Xuid, req.Permission)
+	})
 
-func (u *bedrockUC) BanPlayer(name string, playerName string) error {
-	ban := fmt.Sprintf(`ban "%s"`, playerName)
-	if err := u.SendCommandforAPI(name, ban); err != nil {
-		return err
-	}
-	return nil
 }
 
-func (u *bedrockUC) CreateOrUpdatePermissions(req *dto.PermissionPlayer, worldName string) error {
-	var resultFile []dto.PermissionPlayer
-
-	path := filepath.Join("data/servers", worldName, "permissions.json")
-	result, err := os.ReadFile(path)
+func (u *bedrockUC) CreatePriority(req *dto.Allowlist, worldName, ifMatch string) (string, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(worldName)
 	if err != nil {
-		return err
-	}
-
-	if err := json.Unmarshal(result, &resultFile); err != nil {
-		return err
-	}
-
-	found := false
-
-	for i, r := range resultFile {
-		if r.Xuid == req.Xuid {
-			resultFile[i].Permission = req.Permission
-			found = true
-			break
-		}
-	}
-
-	if !found {
-		resultFile = append(resultFile, dto.PermissionPlayer{
-			Xuid:       req.Xuid,
-			Permission: req.Permission,
-		})
+		return "", fmt.Errorf("server %s not found", worldName)
 	}
-
-	output, err := json.MarshalIndent(resultFile, "", "  ")
+	entry, err := u.allowlistEntry(worlddb.ID, *req)
 	if err != nil {
-		return err
+		return "", err
 	}
+	*req = entry
 
-	return os.WriteFile(path, output, 0644)
+	return u.editAccess(worldName, allowlistFile, ifMatch, func(worldId uint) (bool, error) {
+		return true, u.bedRepo.SetAllowlistEntry(&model.AllowlistEntry{
+			WorldServerId:      worldId,
+			Xuid:               req.Xuid,
+			Name:               req.Name,
+			IgnoresPlayerLimit: req.Priority,
+		})
+	})
 
 }
 
-func (u *bedrockUC) CreatePriority(req *dto.Allowlist, worldName string) error {
-	var resultFile []dto.Allowlist
-
-	path := filepath.Join("data/servers", worldName, "allowlist.json")
-	result, err := os.ReadFile(path)
+func (u *bedrockUC) DeletePriority(xuid, worldName, ifMatch string) (string, error) {
+	xuid, err := u.playerXuid(xuid)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	if err := json.Unmarshal(result, &resultFile); err != nil {
-		return err
-	}
-
-	found := false
-
-	for i, r := range resultFile {
-		if r.Xuid == req.Xuid {
-			resultFile[i].Priority = req.Priority
-			found = true
-			break
-		}
-	}
+	return u.editAccess(worldName, allowlistFile, ifMatch, func(worldId uint) (bool, error) {
+		removed, err := u.bedRepo.DeleteAllowlistEntries(worldId, xuid, "")
+		return len(removed) > 0, err
+	})
 
-	if !found {
-		resultFile = append(resultFile, dto.Allowlist{
-			Xuid:     req.Xuid,
-			Name:     req.Name,
-			Priority: req.Priority,
-		})
-	}
+}
 
-	output, err := json.MarshalIndent(resultFile, "", "  ")
+func (u *bedrockUC) DeletePermission(xuid, worldName, ifMatch string) (string, error) {
+	xuid, err := u.playerXuid(xuid)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	return os.WriteFile(path, output, 0644)
+	return u.editAccess(worldName, permissionsFile, ifMatch, func(worldId uint) (bool, error) {
+		return u.bedRepo.DeletePermission(worldId, xuid)
+	})
 
 }
 
-func (u *bedrockUC) DeletePriority(xuid, worldName string) error {
-	var resultFile []dto.Allowlist
-
-	path := filepath.Join("data/servers", worldName, "allowlist.json")
-	result, err := os.ReadFile(path)
+func (u *bedrockUC) GetPriority(name string) ([]dto.Allowlist, string, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(name)
 	if err != nil {
-		return err
+		return nil, "", fmt.Errorf("server %s not found", name)
 	}
 
-	if err := json.Unmarshal(result, &resultFile); err != nil {
-		return err
-	}
-
-	var newPriority []dto.Allowlist
-	for _, r := range resultFile {
-		if r.Xuid != xuid {
-			continue
-		}
-		newPriority = append(newPriority, dto.Allowlist{
-			Xuid:     r.Xuid,
-			Name:     r.Name,
-			Priority: r.Priority,
-		})
+	list, err := u.worldAllowlist(worlddb.ID)
+	if err != nil {
+		return nil, "", err
 	}
-
-	output, err := json.MarshalIndent(newPriority, "", "  ")
+	output, err := encodeList(list)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
-	return os.WriteFile(path, output, 0644)
+	return list, fileETag(output), nil
 
 }
 
-func (u *bedrockUC) DeletePermission(xuid, worldName string) error {
-	var resultFile []dto.PermissionPlayer
-
-	path := filepath.Join("data/servers", worldName, "permissions.json")
-	result, err := os.ReadFile(path)
+func (u *bedrockUC) GetPermissionPlayer(name string) ([]dto.PermissionPlayer, string, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(name)
 	if err != nil {
-		return err
+		return nil, "", fmt.Errorf("server %s not found", name)
 	}
 
-	if err := json.Unmarshal(result, &resultFile); err != nil {
-		return err
-	}
-
-	var newPermissions []dto.PermissionPlayer
-	for _, r := range resultFile {
-		if r.Xuid != xuid {
-			continue
-		}
-		newPermissions = append(newPermissions, dto.PermissionPlayer{
-			Xuid:       r.Xuid,
-			Permission: r.Permission,
-		})
+	list, err := u.worldPermissions(worlddb.ID)
+	if err != nil {
+		return nil, "", err
 	}
-	output, err := json.MarshalIndent(newPermissions, "", "  ")
+	output, err := encodeList(list)
 	if err != nil {
-		return err
+		return nil, "", err
 	}
 
-	return os.WriteFile(path, output, 0644)
+	return list, fileETag(output), nil
 
 }
 
-func (u *bedrockUC) GetPriority(name string) ([]dto.Allowlist, error) {
-	var resultFile []dto.Allowlist
-
-	path := filepath.Join("data/servers", name, "allowlist.json")
-	result, err := os.ReadFile(path)
+func (u *bedrockUC) GetWorlds() ([]dto.GetWorlds, error) {
+	worlds, err := u.bedRepo.GetWorlds()
 	if err != nil {
 		return nil, err
 	}
 
-	if err := json.Unmarshal(result, &resultFile); err != nil {
-		return nil, err
+	for i := range worlds {
+		state, since := u.stateOf(worlds[i].Name)
+		worlds[i].State = string(state)
+		worlds[i].StateSince = since
 	}
-
-	return resultFile, nil
-
+	return worlds, nil
 }
 
-func (u *bedrockUC) GetPermissionPlayer(name string) ([]dto.PermissionPlayer, error) {
-	var resultFile []dto.PermissionPlayer
-
-	path := filepath.Join("data/servers", name, "permissions.json")
-	result, err := os.ReadFile(path)
+func (u *bedrockUC) GetWorldAndPlayers(name string) (*dto.GetWorldAndPlayers, error) {
+	world, err := u.bedRepo.GetWorldAndPlayers(name)
 	if err != nil {
 		return nil, err
 	}
 
-	if err := json.Unmarshal(result, &resultFile); err != nil {
-		return nil, err
-	}
-
-	return resultFile, nil
-
-}
-
-func (u *bedrockUC) GetWorlds() ([]dto.GetWorlds, error) {
-	return u.bedRepo.GetWorlds()
-}
-
-func (u *bedrockUC) GetWorldAndPlayers(name string) (*dto.GetWorldAndPlayers, error) {
-	return u.bedRepo.GetWorldAndPlayers(name)
+	state, since := u.stateOf(name)
+	world.State = string(state)
+	world.StateSince = since
+	return world, nil
 }
 
 func (u *bedrockUC) GetServerLogs(name string) ([]string, error) {
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"bufio"
+	"fmt"
+	"minecrat_go/dto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const cgroupCPUPeriod = 100000
+
+type cgroupLimits struct {
+	CPUQuota    int // percent of one cpu, 0 is unlimited
+	MemoryMaxMB int
+	PidsMax     int
+}
+
+func (l cgroupLimits) empty() bool {
+	return l.CPUQuota <= 0 && l.MemoryMaxMB <= 0 && l.PidsMax <= 0
+}
+
+func (u *bedrockUC) cgroupPath(name string) string {
+	return filepath.Join(u.cgroupRoot, "world-"+name)
+}
+
+// prepareCgroup creates a fresh cgroup v2 for one launch of a world and
+// writes its limits. Without limits a failure is not fatal, the cgroup is
+// then only missing for usage reporting.
+func (u *bedrockUC) prepareCgroup(name string, limits cgroupLimits) (string, error) {
+	path, err := u.createCgroup(name, limits)
+	if err != nil {
+		if limits.empty() {
+			return "", nil
+		}
+		return "", fmt.Errorf("cgroup for %s: %w", name, err)
+	}
+	return path, nil
+}
+
+func (u *bedrockUC) createCgroup(name string, limits cgroupLimits) (string, error) {
+	if err := os.MkdirAll(u.cgroupRoot, 0755); err != nil {
+		return "", err
+	}
+	// children of the slice can only use controllers enabled on it
+	if err := writeCgroupFile(u.cgroupRoot, "cgroup.subtree_control", "+cpu +memory +pids"); err != nil {
+		return "", err
+	}
+
+	path := u.cgroupPath(name)
+	// a cgroup left over from the previous launch still holds old counters
+	os.Remove(path)
+	if err := os.Mkdir(path, 0755); err != nil && !os.IsExist(err) {
+		return "", err
+	}
+
+	cpu := "max"
+	if limits.CPUQuota > 0 {
+		cpu = strconv.Itoa(limits.CPUQuota * cgroupCPUPeriod / 100)
+	}
+	memory := "max"
+	if limits.MemoryMaxMB > 0 {
+		memory = strconv.FormatInt(int64(limits.MemoryMaxMB)*1024*1024, 10)
+	}
+	pids := "max"
+	if limits.PidsMax > 0 {
+		pids = strconv.Itoa(limits.PidsMax)
+	}
+
+	if err := writeCgroupFile(path, "cpu.max", fmt.Sprintf("%s %d", cpu, cgroupCPUPeriod)); err != nil {
+		return "", err
+	}
+	if err := writeCgroupFile(path, "memory.max", memory); err != nil {
+		return "", err
+	}
+	if err := writeCgroupFile(path, "pids.max", pids); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+func removeCgroup(path string) {
+	if path != "" {
+		os.Remove(path)
+	}
+}
+
+func writeCgroupFile(dir, file, value string) error {
+	return os.WriteFile(filepath.Join(dir, file), []byte(value), 0644)
+}
+
+func readCgroupInt(dir, file string) int64 {
+	raw, err := os.ReadFile(filepath.Join(dir, file))
+	if err != nil {
+		return 0
+	}
+	n, _ := strconv.ParseInt(strings.TrimSpace(string(raw)), 10, 64)
+	return n
+}
+
+// readCgroupKey reads one key of a flat keyed file such as memory.events.
+func readCgroupKey(dir, file, key string) int64 {
+	f, err := os.Open(filepath.Join(dir, file))
+	if err != nil {
+		return 0
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 && fields[0] == key {
+			n, _ := strconv.ParseInt(fields[1], 10, 64)
+			return n
+		}
+	}
+	return 0
+}
+
+func cgroupOOMKills(path string) int64 {
+	if path == "" {
+		return 0
+	}
+	return readCgroupKey(path, "memory.events", "oom_kill")
+}
+
+func (u *bedrockUC) GetResourceUsage(name string) (*dto.ResourceUsage, error) {
+	world, err := u.bedRepo.GetWorldByName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	usage := &dto.ResourceUsage{
+		Name: name,
+		Limits: dto.ResourceLimits{
+			CPUQuota:    world.CPUQuota,
+			MemoryMaxMB: world.MemoryMaxMB,
+			PidsMax:     world.PidsMax,
+		},
+	}
+
+	u.s.RLock()
+	server, ok := u.servers[name]
+	u.s.RUnlock()
+	if !ok || server.Cgroup == "" {
+		return usage, nil
+	}
+
+	path := server.Cgroup
+	usage.Available = true
+	usage.CPUUsageSeconds = float64(readCgroupKey(path, "cpu.stat", "usage_usec")) / 1e6
+	usage.CPUThrottledSeconds = float64(readCgroupKey(path, "cpu.stat", "throttled_usec")) / 1e6
+	usage.MemoryBytes = readCgroupInt(path, "memory.current")
+	usage.MemoryPeakBytes = readCgroupInt(path, "memory.peak")
+	usage.Pids = readCgroupInt(path, "pids.current")
+	usage.OOMKills = cgroupOOMKills(path)
+	return usage, nil
+}
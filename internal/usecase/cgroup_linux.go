@@ -0,0 +1,22 @@
+package usecase
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// placeInCgroup makes the process start directly inside the cgroup, the
+// returned file must stay open until cmd.Start has returned.
+func placeInCgroup(cmd *exec.Cmd, path string) (*os.File, error) {
+	dir, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(dir.Fd())
+	return dir, nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package usecase
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+)
+
+func placeInCgroup(cmd *exec.Cmd, path string) (*os.File, error) {
+	return nil, errors.New("cgroups are only supported on linux")
+}
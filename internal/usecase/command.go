@@ -0,0 +1,165 @@
+package usecase
+
+import (
+	"fmt"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"strings"
+	"time"
+	"unicode"
+)
+
+const maxCommandTimeout = time.Minute
+
+// RunCommand sends a console command and captures the lines the server
+// prints for it. Capture ends on a known terminator, after a quiet period
+// once output started, after a longer grace period when the command prints
+// nothing, or at timeout. Commands of one server run one at a time. Lines
+// the server prints on its own meanwhile are left out.
+func (u *bedrockUC) RunCommand(name, command string, timeout time.Duration) (*dto.CommandResult, error) {
+	command = strings.TrimSpace(command)
+	if command == "" {
+		return nil, fmt.Errorf("%w: command is empty", utils.ErrInvalidCommand)
+	}
+	if hasControl(command) {
+		return nil, fmt.Errorf("%w: command must be a single line", utils.ErrInvalidCommand)
+	}
+	if err := u.requireState(name, "send command to", StateRunning); err != nil {
+		return nil, err
+	}
+
+	u.s.RLock()
+	server, ok := u.servers[name]
+	u.s.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+
+	if timeout <= 0 {
+		timeout = u.commandTimeout
+	}
+	timeout = min(timeout, maxCommandTimeout)
+
+	server.CmdMu.Lock()
+	defer server.CmdMu.Unlock()
+
+	capture := make(chan ServerEvent, 1000)
+	server.LogMu.Lock()
+	server.capture = capture
+	server.LogMu.Unlock()
+	defer func() {
+		server.LogMu.Lock()
+		server.capture = nil
+		server.LogMu.Unlock()
+	}()
+
+	started := time.Now()
+	if err := server.writeLine(command); err != nil {
+		return nil, err
+	}
+
+	result := &dto.CommandResult{Command: command, Lines: []string{}}
+	var events []ServerEvent
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	quiet := time.NewTimer(u.commandGrace)
+	defer quiet.Stop()
+
+capture:
+	for {
+		select {
+		case ev := <-capture:
+			if unsolicited(ev) {
+				continue
+			}
+			result.Lines = append(result.Lines, ev.Raw)
+			events = append(events, ev)
+			if commandDone(command, events) {
+				break capture
+			}
+			quiet.Reset(u.commandQuiet)
+		case <-quiet.C:
+			break capture
+		case <-deadline.C:
+			result.TimedOut = true
+			break capture
+		case <-server.done:
+			break capture
+		}
+	}
+
+	result.Duration = time.Since(started).Round(time.Millisecond).String()
+	return result, nil
+}
+
+// consoleText makes user input safe inside a console command. A line break
+// would end the command and run the rest as another one, so every control
+// character becomes a space.
+func consoleText(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, s)
+}
+
+func hasControl(s string) bool {
+	return strings.IndexFunc(s, unicode.IsControl) >= 0
+}
+
+// unsolicited tells the lines a server prints on its own, they are no answer
+// to a command even when they arrive while one is captured.
+func unsolicited(ev ServerEvent) bool {
+	switch ev.Kind {
+	case KindPlayerConnected, KindPlayerDisconnected, KindPlayerSpawned,
+		KindAutosave, KindContentLog, KindStarted, KindVersion, KindPortBound, KindPortOccupied:
+		return true
+	}
+	return false
+}
+
+// checkedCommand runs a command and fails when the server rejects it.
+func (u *bedrockUC) checkedCommand(name, command string) error {
+	result, err := u.RunCommand(name, command, 0)
+	if err != nil {
+		return err
+	}
+	for _, line := range result.Lines {
+		ev := parseServerLine(name, line, time.Now())
+		if ev.Kind == KindCommandError {
+			return fmt.Errorf("%w: %s", utils.ErrCommandRejected, ev.Message)
+		}
+	}
+	return nil
+}
+
+// commandDone recognizes the last line of commands with a known answer.
+func commandDone(command string, events []ServerEvent) bool {
+	if events[len(events)-1].Kind == KindCommandError {
+		return true
+	}
+
+	switch strings.ToLower(strings.Fields(command)[0]) {
+	case "list":
+		// "There are N/M players online:" is followed by the names line
+		for i, ev := range events {
+			if ev.Kind == KindPlayerList {
+				return i < len(events)-1
+			}
+		}
+	case "kick":
+		return strings.HasPrefix(events[len(events)-1].Message, "Kicked ")
+	case "ability", "difficulty", "defaultgamemode":
+		// a single line either way
+		return true
+	case "gamerule":
+		// setting a rule answers with one line, listing them with more
+		return len(strings.Fields(command)) == 3
+	case "allowlist":
+		// on, off and reload answer with one line, list with more
+		fields := strings.Fields(command)
+		return len(fields) == 2 && fields[1] != "list"
+	}
+	return false
+}
@@ -0,0 +1,162 @@
+package usecase
+
+import (
+	"errors"
+	"minecrat_go/helper/utils"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func logLines(lines ...string) []ServerEvent {
+	events := make([]ServerEvent, 0, len(lines))
+	for _, line := range lines {
+		events = append(events, parseServerLine("alpha", "[2026-01-02 03:04:05:006 INFO] "+line, time.Now()))
+	}
+	return events
+}
+
+func TestCommandDone(t *testing.T) {
+	tests := []struct {
+		command string
+		lines   []string
+		want    bool
+	}{
+		{"list", []string{"There are 1/10 players online:"}, false},
+		{"list", []string{"There are 1/10 players online:", "Steve"}, true},
+		{"list", []string{"There are 0/10 players online:", ""}, true},
+		{"kick Steve", []string{"Kicked Steve from the game"}, true},
+		{"kick Steve bye", []string{"Kicked Steve from the game: 'bye'"}, true},
+		{"ability Steve mute true", []string{"The ability mute for Steve has been updated"}, true},
+		{"difficulty hard", []string{"Set game difficulty to hard"}, true},
+		{"gamerule pvp false", []string{"Game rule pvp has been updated to false"}, true},
+		{"gamerule", []string{"commandblockoutput = true, dodaylightcycle = true"}, false},
+		{"allowlist on", []string{"Allowlist enabled."}, true},
+		{"allowlist list", []string{"Allowlist:"}, false},
+		{"save hold", []string{"Saving..."}, false},
+		{"foo", []string{"Unknown command: foo. Please check that the command exists"}, true},
+		{"gamerule foo", []string{"Syntax error: Unexpected \"foo\""}, true},
+		{"tell Steve hi", []string{"No targets matched selector"}, true},
+	}
+
+	for _, tt := range tests {
+		if got := commandDone(tt.command, logLines(tt.lines...)); got != tt.want {
+			t.Errorf("commandDone(%q, %q) = %v, want %v", tt.command, tt.lines, got, tt.want)
+		}
+	}
+}
+
+func TestUnsolicited(t *testing.T) {
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"Player connected: Steve, xuid: 2535400000000001", true},
+		{"Player disconnected: Steve, xuid: 2535400000000001", true},
+		{"Player Spawned: Steve xuid: 2535400000000001", true},
+		{"Running AutoCompaction...", true},
+		{"There are 1/10 players online:", false},
+		{"Kicked Steve from the game", false},
+		{"Unknown command: foo.", false},
+		{"Saving...", false},
+	}
+	for _, tt := range tests {
+		if got := unsolicited(logLines(tt.line)[0]); got != tt.want {
+			t.Errorf("unsolicited(%q) = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestRunCommandCapture(t *testing.T) {
+	fake := NewFakeRuntime()
+	u, _ := newTestUC(t, fake, testWorld("alpha", 1))
+	startWorld(t, u, "alpha")
+	joinPlayer(t, u, fake, "alpha", "Steve", "2535400000000001")
+
+	// a long quiet period shows which commands end on a terminator
+	u.commandQuiet = 500 * time.Millisecond
+
+	tests := []struct {
+		name     string
+		command  string
+		timeout  time.Duration
+		lines    int
+		timedOut bool
+		min, max time.Duration
+	}{
+		{name: "terminator ends list", command: "list", lines: 2, max: 400 * time.Millisecond},
+		{name: "an error ends the capture", command: "foo", lines: 1, max: 400 * time.Millisecond},
+		{name: "quiet period ends save hold", command: "save hold", lines: 1, min: 450 * time.Millisecond},
+		{name: "grace period ends a silent command", command: "tell Steve hi", min: 80 * time.Millisecond, max: 400 * time.Millisecond},
+		{name: "timeout caps the quiet period", command: "save hold", timeout: 200 * time.Millisecond, lines: 1, timedOut: true, min: 150 * time.Millisecond, max: 450 * time.Millisecond},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			begin := time.Now()
+			result, err := u.RunCommand("alpha", tt.command, tt.timeout)
+			if err != nil {
+				t.Fatal(err)
+			}
+			took := time.Since(begin)
+
+			if len(result.Lines) != tt.lines {
+				t.Errorf("lines = %q, want %d lines", result.Lines, tt.lines)
+			}
+			if result.TimedOut != tt.timedOut {
+				t.Errorf("timed out = %v, want %v", result.TimedOut, tt.timedOut)
+			}
+			if took < tt.min || (tt.max > 0 && took > tt.max) {
+				t.Errorf("took %s, want between %s and %s", took, tt.min, tt.max)
+			}
+		})
+	}
+}
+
+func TestRunCommandIgnoresInterleavedOutput(t *testing.T) {
+	fake := NewFakeRuntime()
+	u, _ := newTestUC(t, fake, testWorld("alpha", 1))
+	startWorld(t, u, "alpha")
+	p := fake.Process("alpha")
+	u.commandQuiet = 300 * time.Millisecond
+
+	go func() {
+		// lands inside the quiet period after "Saving..."
+		time.Sleep(100 * time.Millisecond)
+		p.Connect("Alex", "2535400000000002")
+		p.Emit("INFO", "Running AutoCompaction...")
+	}()
+
+	result, err := u.RunCommand("alpha", "save hold", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Lines) != 1 || !strings.HasSuffix(result.Lines[0], "Saving...") {
+		t.Errorf("lines = %q, want only the answer to save hold", result.Lines)
+	}
+
+	// the join itself was still handled
+	eventually(t, "Alex to join", func() bool {
+		players, _ := u.GetOnlinePlayers("alpha")
+		return len(players) == 1
+	})
+}
+
+func TestRunCommandRejectsControl(t *testing.T) {
+	fake := NewFakeRuntime()
+	u, _ := newTestUC(t, fake, testWorld("alpha", 1))
+	startWorld(t, u, "alpha")
+
+	for _, command := range []string{"list\nstop", "list\rstop", "say hi\x00"} {
+		if _, err := u.RunCommand("alpha", command, 0); !errors.Is(err, utils.ErrInvalidCommand) {
+			t.Errorf("RunCommand(%q) err = %v, want ErrInvalidCommand", command, err)
+		}
+		if err := u.SendCommandforAPI("alpha", command); !errors.Is(err, utils.ErrInvalidCommand) {
+			t.Errorf("SendCommandforAPI(%q) err = %v, want ErrInvalidCommand", command, err)
+		}
+	}
+	if commands := sentCommands(t, u, fake, "alpha"); !slices.Equal(commands, []string{"list"}) {
+		t.Errorf("console got %q, want only the list", commands)
+	}
+}
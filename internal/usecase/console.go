@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"sync"
+)
+
+const consoleBuffer = 256
+
+// consoleHub fans the output of one world out to every attached viewer. It
+// belongs to the world name, so viewers stay attached across restarts.
+type consoleHub struct {
+	mu   sync.Mutex
+	subs map[*consoleSub]struct{}
+}
+
+type consoleSub struct {
+	lines chan string
+	once  sync.Once
+}
+
+func (s *consoleSub) close() {
+	s.once.Do(func() { close(s.lines) })
+}
+
+func (u *bedrockUC) consoleHub(name string) *consoleHub {
+	u.consoleMu.Lock()
+	defer u.consoleMu.Unlock()
+
+	hub, ok := u.consoles[name]
+	if !ok {
+		hub = &consoleHub{subs: make(map[*consoleSub]struct{})}
+		u.consoles[name] = hub
+	}
+	return hub
+}
+
+// publish never blocks the scanner: a viewer whose buffer is full is dropped.
+func (h *consoleHub) publish(world, line string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		select {
+		case sub.lines <- line:
+		default:
+			log.Printf("server %s: dropping slow console viewer", world)
+			delete(h.subs, sub)
+			sub.close()
+		}
+	}
+}
+
+func (h *consoleHub) remove(sub *consoleSub) {
+	h.mu.Lock()
+	delete(h.subs, sub)
+	h.mu.Unlock()
+	sub.close()
+}
+
+func (h *consoleHub) closeAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		sub.close()
+	}
+}
+
+// SubscribeConsole attaches a viewer to the console of a world. The channel
+// first yields up to history recent lines, then every new line, and is
+// closed when the viewer falls behind or the manager shuts down. cancel
+// detaches the viewer.
+func (u *bedrockUC) SubscribeConsole(name string, history int) (<-chan string, func(), error) {
+	if _, err := u.bedRepo.GetWorldByName(name); err != nil {
+		return nil, nil, fmt.Errorf("server %s not found", name)
+	}
+
+	hub := u.consoleHub(name)
+	sub := &consoleSub{lines: make(chan string, consoleBuffer+history)}
+
+	u.s.RLock()
+	server, ok := u.servers[name]
+	u.s.RUnlock()
+
+	// holding LogMu keeps the scanner from appending and publishing between
+	// the history snapshot and the subscription, so no line is lost or doubled
+	if ok {
+		server.LogMu.Lock()
+		defer server.LogMu.Unlock()
+		start := max(len(server.Logs)-history, 0)
+		for _, line := range server.Logs[start:] {
+			sub.lines <- line
+		}
+	}
+
+	hub.mu.Lock()
+	hub.subs[sub] = struct{}{}
+	hub.mu.Unlock()
+
+	return sub.lines, func() { hub.remove(sub) }, nil
+}
+
+func (u *bedrockUC) closeConsoles() {
+	u.consoleMu.Lock()
+	defer u.consoleMu.Unlock()
+
+	for _, hub := range u.consoles {
+		hub.closeAll()
+	}
+}
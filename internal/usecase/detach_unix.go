@@ -0,0 +1,17 @@
+//go:build unix
+
+package usecase
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// detachProcess puts the server in its own process group so a Ctrl-C on the
+// manager does not reach it and it can outlive the manager.
+func detachProcess(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
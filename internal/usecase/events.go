@@ -0,0 +1,120 @@
+package usecase
+
+import (
+	"fmt"
+	"minecrat_go/dto"
+	"sync"
+	"time"
+)
+
+const eventSubBuffer = 256
+
+// eventStream keeps the last events of every world in a ring buffer, so a
+// client that reconnects with Last-Event-ID can resume where it left off.
+type eventStream struct {
+	mu     sync.Mutex
+	nextID uint64
+	ring   []dto.Event
+	head   int // index of the oldest event
+	size   int
+	subs   map[*eventSub]struct{}
+}
+
+type eventSub struct {
+	world  string
+	events chan dto.Event
+	once   sync.Once
+}
+
+func (s *eventSub) close() {
+	s.once.Do(func() { close(s.events) })
+}
+
+func newEventStream(capacity int) *eventStream {
+	return &eventStream{
+		ring: make([]dto.Event, max(capacity, 1)),
+		subs: make(map[*eventSub]struct{}),
+	}
+}
+
+// publish stores the event and hands it to every subscriber of the world,
+// a subscriber whose buffer is full is dropped instead of blocking.
+func (s *eventStream) publish(world, typ string, data interface{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextID++
+	event := dto.Event{ID: s.nextID, World: world, Type: typ, Time: time.Now(), Data: data}
+
+	if s.size < len(s.ring) {
+		s.ring[(s.head+s.size)%len(s.ring)] = event
+		s.size++
+	} else {
+		s.ring[s.head] = event
+		s.head = (s.head + 1) % len(s.ring)
+	}
+
+	for sub := range s.subs {
+		if sub.world != "" && sub.world != world {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			delete(s.subs, sub)
+			sub.close()
+		}
+	}
+}
+
+// subscribe replays the buffered events after lastID, 0 replays nothing.
+// world "" receives the events of every world.
+func (s *eventStream) subscribe(world string, lastID uint64) (<-chan dto.Event, func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var backlog []dto.Event
+	if lastID > 0 {
+		for i := 0; i < s.size; i++ {
+			event := s.ring[(s.head+i)%len(s.ring)]
+			if event.ID > lastID && (world == "" || event.World == world) {
+				backlog = append(backlog, event)
+			}
+		}
+	}
+
+	sub := &eventSub{world: world, events: make(chan dto.Event, eventSubBuffer+len(backlog))}
+	for _, event := range backlog {
+		sub.events <- event
+	}
+	s.subs[sub] = struct{}{}
+
+	return sub.events, func() {
+		s.mu.Lock()
+		delete(s.subs, sub)
+		s.mu.Unlock()
+		sub.close()
+	}
+}
+
+func (s *eventStream) closeAll() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for sub := range s.subs {
+		delete(s.subs, sub)
+		sub.close()
+	}
+}
+
+// SubscribeEvents streams the events of one world, or of every world when
+// name is empty, starting after lastID.
+func (u *bedrockUC) SubscribeEvents(name string, lastID uint64) (<-chan dto.Event, func(), error) {
+	if name != "" {
+		if _, err := u.bedRepo.GetWorldByName(name); err != nil {
+			return nil, nil, fmt.Errorf("server %s not found", name)
+		}
+	}
+	events, cancel := u.events.subscribe(name, lastID)
+	return events, cancel, nil
+}
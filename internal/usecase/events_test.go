@@ -0,0 +1,124 @@
+package usecase
+
+import (
+	"minecrat_go/dto"
+	"testing"
+)
+
+// drain reads what is buffered on a subscription without blocking.
+func drain(events <-chan dto.Event) (ids []uint64, open bool) {
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return ids, false
+			}
+			ids = append(ids, event.ID)
+		default:
+			return ids, true
+		}
+	}
+}
+
+func equalIDs(a, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestEventStreamReplay(t *testing.T) {
+	// ids 1-8 alternate between alpha and beta, a ring of 5 keeps 4-8
+	s := newEventStream(5)
+	for i := 0; i < 8; i++ {
+		world := "alpha"
+		if i%2 == 1 {
+			world = "beta"
+		}
+		s.publish(world, dto.EventLog, nil)
+	}
+
+	tests := []struct {
+		name   string
+		world  string
+		lastID uint64
+		want   []uint64
+	}{
+		{"no last id replays nothing", "alpha", 0, nil},
+		{"resume inside the ring", "alpha", 5, []uint64{7}},
+		{"resume inside the ring of another world", "beta", 4, []uint64{6, 8}},
+		{"resume after an evicted id replays the whole ring", "alpha", 1, []uint64{5, 7}},
+		{"global resume inside the ring", "", 5, []uint64{6, 7, 8}},
+		{"global resume after an evicted id", "", 2, []uint64{4, 5, 6, 7, 8}},
+		{"resume at the newest id", "", 8, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			events, cancel := s.subscribe(tt.world, tt.lastID)
+			defer cancel()
+			if got, _ := drain(events); !equalIDs(got, tt.want) {
+				t.Errorf("replayed %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEventStreamLive(t *testing.T) {
+	s := newEventStream(16)
+	alpha, cancelAlpha := s.subscribe("alpha", 0)
+	defer cancelAlpha()
+	all, cancelAll := s.subscribe("", 0)
+	defer cancelAll()
+
+	s.publish("alpha", dto.EventLog, nil)
+	s.publish("beta", dto.EventLog, nil)
+
+	if got, _ := drain(alpha); !equalIDs(got, []uint64{1}) {
+		t.Errorf("alpha got %v, want [1]", got)
+	}
+	if got, _ := drain(all); !equalIDs(got, []uint64{1, 2}) {
+		t.Errorf("global got %v, want [1 2]", got)
+	}
+}
+
+func TestEventStreamDropsSlowSubscriber(t *testing.T) {
+	s := newEventStream(eventSubBuffer * 2)
+	slow, cancelSlow := s.subscribe("", 0)
+	defer cancelSlow()
+	fast, cancelFast := s.subscribe("", 0)
+	defer cancelFast()
+
+	var got []uint64
+	for i := 0; i < eventSubBuffer+1; i++ {
+		s.publish("alpha", dto.EventLog, nil)
+		ids, _ := drain(fast)
+		got = append(got, ids...)
+	}
+
+	ids, open := drain(slow)
+	if open {
+		t.Fatal("a subscriber with a full buffer was kept")
+	}
+	if len(ids) != eventSubBuffer {
+		t.Errorf("slow subscriber got %d events before the drop, want %d", len(ids), eventSubBuffer)
+	}
+	if len(got) != eventSubBuffer+1 {
+		t.Errorf("fast subscriber got %d events, want %d", len(got), eventSubBuffer+1)
+	}
+
+	// the dropped subscriber resumes from the last id it saw
+	resumed, cancel := s.subscribe("", ids[len(ids)-1])
+	defer cancel()
+	if rest, _ := drain(resumed); !equalIDs(rest, []uint64{eventSubBuffer + 1}) {
+		t.Errorf("resume replayed %v, want [%d]", rest, eventSubBuffer+1)
+	}
+
+	// cancelling a dropped subscription twice is harmless
+	cancelSlow()
+}
@@ -0,0 +1,188 @@
+package usecase
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"minecrat_go/helper/utils"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+const (
+	permissionsFile = "permissions.json"
+	allowlistFile   = "allowlist.json"
+)
+
+// fileStore serializes the writes of the json files bedrock_server reads
+// from a world directory. Every file has its own lock, so edits of different
+// worlds do not wait for each other.
+type fileStore struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+func worldFilePath(world, file string) string {
+	return filepath.Join("data/servers", world, file)
+}
+
+func (s *fileStore) lock(path string) *sync.Mutex {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.locks == nil {
+		s.locks = make(map[string]*sync.Mutex)
+	}
+	l, ok := s.locks[path]
+	if !ok {
+		l = &sync.Mutex{}
+		s.locks[path] = l
+	}
+	return l
+}
+
+// fileETag is the strong etag of a file content.
+func fileETag(data []byte) string {
+	sum := sha256.Sum256(data)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches checks an If-Match header against the current etag, an empty
+// header or * always matches.
+func etagMatches(ifMatch, etag string) bool {
+	if ifMatch == "" {
+		return true
+	}
+	for _, tag := range strings.Split(ifMatch, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || tag == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// writeFileAtomic replaces path with data through a synced temp file in the
+// same directory, a crash leaves either the old or the new content.
+func writeFileAtomic(path string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(path)
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(perm); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// the rename itself is durable once the directory is synced
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
+
+// encodeList renders a json array the way the files are written.
+func encodeList(v interface{}) ([]byte, error) {
+	output, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	// the files are json arrays, an empty slice must not become null
+	if bytes.Equal(output, []byte("null")) {
+		output = []byte("[]")
+	}
+	return output, nil
+}
+
+func readList(world, file string, v interface{}) error {
+	data, err := os.ReadFile(worldFilePath(world, file))
+	if err != nil {
+		return err
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%s: %w", file, err)
+	}
+	return nil
+}
+
+// read loads a json file of a world into v.
+func (s *fileStore) read(world, file string, v interface{}) error {
+	l := s.lock(worldFilePath(world, file))
+	l.Lock()
+	defer l.Unlock()
+
+	return readList(world, file, v)
+}
+
+// write replaces a file of a world with the output of render.
+func (s *fileStore) write(world, file string, render func() ([]byte, error)) error {
+	path := worldFilePath(world, file)
+	l := s.lock(path)
+	l.Lock()
+	defer l.Unlock()
+
+	data, err := render()
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(path, data, 0644)
+}
+
+// edit runs change between two renderings of a file of a world and writes
+// the second one when change reports a modification. A non empty ifMatch
+// must match the etag of the first rendering. The etag of the resulting
+// content is returned.
+func (s *fileStore) edit(world, file, ifMatch string, render func() ([]byte, error), change func() (bool, error)) (string, error) {
+	path := worldFilePath(world, file)
+	l := s.lock(path)
+	l.Lock()
+	defer l.Unlock()
+
+	data, err := render()
+	if err != nil {
+		return "", err
+	}
+	etag := fileETag(data)
+	if !etagMatches(ifMatch, etag) {
+		return "", fmt.Errorf("%w: %s changed, current etag %s", utils.ErrPreconditionFailed, file, etag)
+	}
+
+	changed, err := change()
+	if err != nil {
+		return "", err
+	}
+	if !changed {
+		return etag, nil
+	}
+
+	if data, err = render(); err != nil {
+		return "", err
+	}
+	if err := writeFileAtomic(path, data, 0644); err != nil {
+		return "", err
+	}
+	return fileETag(data), nil
+}
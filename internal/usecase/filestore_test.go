@@ -0,0 +1,135 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"minecrat_go/helper/utils"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+// listFile keeps a list in memory and renders it like the world files.
+type listFile struct {
+	items []string
+}
+
+func (f *listFile) render() ([]byte, error) { return encodeList(f.items) }
+
+func TestFileStoreEditIfMatch(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll(filepath.Dir(worldFilePath("alpha", allowlistFile)), 0755); err != nil {
+		t.Fatal(err)
+	}
+	file := &listFile{items: []string{"Steve"}}
+	data, _ := file.render()
+	current := fileETag(data)
+
+	tests := []struct {
+		name    string
+		ifMatch string
+		wantErr bool
+	}{
+		{"no header", "", false},
+		{"any", "*", false},
+		{"current etag", current, false},
+		{"current etag in a list", `"stale", ` + current, false},
+		{"stale etag", `"stale"`, true},
+	}
+
+	var s fileStore
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			file.items = []string{"Steve"}
+			called := false
+			_, err := s.edit("alpha", allowlistFile, tt.ifMatch, file.render, func() (bool, error) {
+				called = true
+				return false, nil
+			})
+			if tt.wantErr {
+				if !errors.Is(err, utils.ErrPreconditionFailed) {
+					t.Errorf("err = %v, want ErrPreconditionFailed", err)
+				}
+				if called {
+					t.Error("the change ran on a stale etag")
+				}
+				return
+			}
+			if err != nil || !called {
+				t.Errorf("err = %v called = %v, want the change to run", err, called)
+			}
+		})
+	}
+}
+
+func TestFileStoreEditSerializes(t *testing.T) {
+	t.Chdir(t.TempDir())
+	if err := os.MkdirAll(filepath.Dir(worldFilePath("alpha", allowlistFile)), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	var s fileStore
+	file := &listFile{}
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.edit("alpha", allowlistFile, "", file.render, func() (bool, error) {
+				// read, wait, write: an edit running alongside would be lost
+				items := file.items
+				time.Sleep(time.Millisecond)
+				file.items = append(items[:len(items):len(items)], fmt.Sprint(i))
+				return true, nil
+			})
+			if err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var got []string
+	if err := s.read("alpha", allowlistFile, &got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 20 {
+		t.Errorf("file has %d entries, want 20: %q", len(got), got)
+	}
+}
+
+func TestWriteFileAtomic(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, allowlistFile)
+
+	if err := writeFileAtomic(path, []byte("[]"), 0640); err != nil {
+		t.Fatal(err)
+	}
+	if data, _ := os.ReadFile(path); string(data) != "[]" {
+		t.Errorf("content = %q, want []", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0640 {
+		t.Errorf("mode = %s, want 0640", info.Mode().Perm())
+	}
+
+	// a directory in the way fails the rename after the temp file was written
+	blocked := filepath.Join(dir, "blocked")
+	if err := os.MkdirAll(filepath.Join(blocked, "child"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := writeFileAtomic(blocked, []byte("[]"), 0644); err == nil {
+		t.Error("replacing a directory succeeded")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		if entry.Name() != allowlistFile && entry.Name() != "blocked" {
+			t.Errorf("temp file %s was left behind", entry.Name())
+		}
+	}
+}
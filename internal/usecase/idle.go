@@ -0,0 +1,113 @@
+package usecase
+
+import (
+	"log"
+	"minecrat_go/dto"
+	"time"
+)
+
+// playerJoined records a connect line and cancels a pending idle shutdown.
+// It returns the previous roster entry if the player was already online.
+func (u *bedrockUC) playerJoined(world string, server *BedrockServer, xuid, player string, at time.Time) (onlinePlayer, bool) {
+	server.PlayerMu.Lock()
+	defer server.PlayerMu.Unlock()
+
+	prev, ok := server.Online[xuid]
+	server.Online[xuid] = onlinePlayer{Xuid: xuid, Name: player, JoinedAt: at}
+	u.events.publish(world, dto.EventPlayerJoined, dto.PlayerEvent{Name: player, Xuid: xuid})
+	if server.idleTimer != nil {
+		server.idleTimer.Stop()
+		server.idleTimer = nil
+	}
+	return prev, ok
+}
+
+// playerLeft records a disconnect line and arms the idle shutdown once the
+// last player is gone. It returns the roster entry of the player.
+func (u *bedrockUC) playerLeft(world string, server *BedrockServer, xuid string) (onlinePlayer, bool) {
+	server.PlayerMu.Lock()
+	defer server.PlayerMu.Unlock()
+
+	player, ok := server.Online[xuid]
+	u.events.publish(world, dto.EventPlayerLeft, dto.PlayerEvent{Name: player.Name, Xuid: xuid})
+	delete(server.Online, xuid)
+	if len(server.Online) == 0 {
+		u.armIdleLocked(world, server)
+	}
+	return player, ok
+}
+
+func (u *bedrockUC) armIdle(world string, server *BedrockServer) {
+	server.PlayerMu.Lock()
+	defer server.PlayerMu.Unlock()
+
+	if len(server.Online) == 0 {
+		u.armIdleLocked(world, server)
+	}
+}
+
+// armIdleLocked starts the idle timer of a world, the caller must hold
+// PlayerMu.
+func (u *bedrockUC) armIdleLocked(world string, server *BedrockServer) {
+	u.s.RLock()
+	sup, ok := u.supervisors[world]
+	u.s.RUnlock()
+	if !ok {
+		return
+	}
+
+	sup.mu.Lock()
+	after := sup.idleAfter
+	sup.mu.Unlock()
+	if after <= 0 {
+		return
+	}
+
+	if server.idleTimer != nil {
+		server.idleTimer.Stop()
+	}
+	server.idleTimer = time.AfterFunc(after, func() {
+		u.idleShutdown(world, server, after)
+	})
+}
+
+func (u *bedrockUC) idleShutdown(world string, server *BedrockServer, after time.Duration) {
+	server.PlayerMu.Lock()
+	online := len(server.Online)
+	server.idleTimer = nil
+	server.PlayerMu.Unlock()
+	if online > 0 {
+		return
+	}
+
+	u.s.RLock()
+	current := u.servers[world]
+	u.s.RUnlock()
+	if current != server {
+		return
+	}
+	if state, _ := u.stateOf(world); state != StateRunning {
+		return
+	}
+
+	log.Printf("server %s: no players online for %s, stopping", world, after)
+	if _, err := u.StopServer(world, 0); err != nil {
+		log.Printf("server %s: idle shutdown failed: %s", world, err)
+	}
+}
+
+func (s *BedrockServer) stopIdle() {
+	s.PlayerMu.Lock()
+	defer s.PlayerMu.Unlock()
+
+	if s.idleTimer != nil {
+		s.idleTimer.Stop()
+		s.idleTimer = nil
+	}
+}
+
+func (s *BedrockServer) onlineCount() int {
+	s.PlayerMu.Lock()
+	defer s.PlayerMu.Unlock()
+	return len(s.Online)
+}
@@ -0,0 +1,97 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"log"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"sync"
+)
+
+// StartAutostart boots the autostart worlds group by group in start_order.
+// Worlds of one group start in parallel, bounded by BEDROCK_AUTOSTART_PARALLEL,
+// and the next group waits until the previous one is ready.
+func (u *bedrockUC) StartAutostart(ctx context.Context) {
+	worlds, err := u.bedRepo.GetAutostartWorlds()
+	if err != nil {
+		log.Printf("autostart: load worlds failed: %s", err)
+		return
+	}
+
+	sem := make(chan struct{}, u.autostartParallel)
+	for i := 0; i < len(worlds); {
+		order := worlds[i].StartOrder
+		var wg sync.WaitGroup
+
+		for ; i < len(worlds) && worlds[i].StartOrder == order; i++ {
+			world := worlds[i]
+			if state, _ := u.stateOf(world.Name); state != StateStopped {
+				continue
+			}
+
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				wg.Wait()
+				return
+			}
+
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-sem }()
+
+				req := dto.StartServerReq{Name: world.Name, WorldId: world.ID, Port: world.Port, PortV6: world.PortV6, Wait: true}
+				if _, err := u.StartServer(&req); err != nil {
+					log.Printf("server %s: autostart failed: %s", world.Name, err)
+				}
+			}()
+		}
+		wg.Wait()
+
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// StopAll gracefully stops every world in parallel and cancels pending
+// restarts and scheduled countdowns, it is called when the manager shuts
+// down.
+func (u *bedrockUC) StopAll() {
+	if u.closing.Swap(true) {
+		return
+	}
+	close(u.shutdown)
+	// a running schedule gives up once its countdown is woken up or its
+	// world is stopped below, so it is only waited for at the end
+	jobs := u.cron.Stop()
+
+	u.s.RLock()
+	names := make([]string, 0, len(u.supervisors))
+	for name := range u.supervisors {
+		names = append(names, name)
+	}
+	u.s.RUnlock()
+
+	var wg sync.WaitGroup
+	for _, name := range names {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, err := u.StopServer(name, 0)
+			if err != nil {
+				if !errors.Is(err, utils.ErrInvalidState) {
+					log.Printf("server %s: stop on shutdown failed: %s", name, err)
+				}
+				return
+			}
+			log.Printf("server %s stopped on shutdown (%s)", name, result.Method)
+		}()
+	}
+	wg.Wait()
+	<-jobs.Done()
+	u.closeConsoles()
+	u.events.closeAll()
+}
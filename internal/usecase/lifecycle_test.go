@@ -0,0 +1,191 @@
+package usecase
+
+import (
+	"minecrat_go/dto"
+	"strings"
+	"testing"
+)
+
+func startWorld(t *testing.T, u *bedrockUC, name string) *dto.StartServerResult {
+	t.Helper()
+	result, err := u.StartServer(&dto.StartServerReq{Name: name, Wait: true})
+	if err != nil {
+		t.Fatalf("start %s: %s", name, err)
+	}
+	return result
+}
+
+func TestStartServerWait(t *testing.T) {
+	fake := NewFakeRuntime()
+	u, _ := newTestUC(t, fake, testWorld("alpha", 1))
+
+	result := startWorld(t, u, "alpha")
+	if !result.Ready || result.State != string(StateRunning) {
+		t.Fatalf("got ready=%v state=%s, want a running server", result.Ready, result.State)
+	}
+	if result.Version != fake.Version {
+		t.Errorf("version = %q, want %q", result.Version, fake.Version)
+	}
+	if result.PortV4 != 19134 || result.PortV6 != 19135 {
+		t.Errorf("ports = %d/%d, want 19134/19135", result.PortV4, result.PortV6)
+	}
+
+	if _, err := u.StartServer(&dto.StartServerReq{Name: "alpha"}); err == nil {
+		t.Error("second start of a running server succeeded")
+	}
+}
+
+func TestStartServerPortOccupied(t *testing.T) {
+	fake := NewFakeRuntime()
+	fake.PortOccupied = true
+	u, _ := newTestUC(t, fake, testWorld("alpha", 1))
+
+	if _, err := u.StartServer(&dto.StartServerReq{Name: "alpha", Wait: true}); err == nil {
+		t.Fatal("start on an occupied port succeeded")
+	}
+	eventually(t, "the world to leave starting", func() bool {
+		state, _ := u.stateOf("alpha")
+		return state != StateStarting && state != StateRunning
+	})
+}
+
+func TestStopServer(t *testing.T) {
+	tests := []struct {
+		name          string
+		hangOnStop    bool
+		ignoreSigterm bool
+		method        string
+	}{
+		{name: "stop command", method: dto.StopMethodCommand},
+		{name: "escalates to sigterm", hangOnStop: true, method: dto.StopMethodTerm},
+		{name: "escalates to sigkill", hangOnStop: true, ignoreSigterm: true, method: dto.StopMethodKill},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fake := NewFakeRuntime()
+			fake.HangOnStop = tt.hangOnStop
+			fake.IgnoreSigterm = tt.ignoreSigterm
+			world := testWorld("alpha", 1)
+			world.RestartPolicy = RestartAlways
+			u, _ := newTestUC(t, fake, world)
+			startWorld(t, u, "alpha")
+
+			result, err := u.StopServer("alpha", 0)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Method != tt.method {
+				t.Errorf("method = %s, want %s", result.Method, tt.method)
+			}
+
+			// an expected exit is not restarted even with restart always
+			state, _ := u.stateOf("alpha")
+			if state != StateStopped {
+				t.Errorf("state = %s, want stopped", state)
+			}
+		})
+	}
+}
+
+func TestCrashRestart(t *testing.T) {
+	fake := NewFakeRuntime()
+	world := testWorld("alpha", 1)
+	world.RestartPolicy = RestartOnFailure
+	u, _ := newTestUC(t, fake, world)
+	startWorld(t, u, "alpha")
+
+	first := fake.Process("alpha")
+	first.Crash(3)
+
+	eventually(t, "the crashed world to come back", func() bool {
+		p := fake.Process("alpha")
+		state, _ := u.stateOf("alpha")
+		return p != first && state == StateRunning
+	})
+
+	status, err := u.GetServerStatus("alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Restarts != 1 {
+		t.Errorf("restarts = %d, want 1", status.Restarts)
+	}
+	if status.LastExit == nil || status.LastExit.Code != 3 || status.LastExit.Expected {
+		t.Errorf("last exit = %+v, want an unexpected exit with code 3", status.LastExit)
+	}
+}
+
+func TestCrashWithoutRestart(t *testing.T) {
+	fake := NewFakeRuntime()
+	u, _ := newTestUC(t, fake, testWorld("alpha", 1))
+	startWorld(t, u, "alpha")
+
+	first := fake.Process("alpha")
+	first.Crash(3)
+
+	eventually(t, "the world to be crashed", func() bool {
+		state, _ := u.stateOf("alpha")
+		return state == StateCrashed
+	})
+	if fake.Process("alpha") != first {
+		t.Error("a world with restart never was restarted")
+	}
+}
+
+func TestRunCommand(t *testing.T) {
+	fake := NewFakeRuntime()
+	u, _ := newTestUC(t, fake, testWorld("alpha", 1))
+	startWorld(t, u, "alpha")
+
+	joinPlayer(t, u, fake, "alpha", "Steve", "2535400000000001")
+
+	result, err := u.RunCommand("alpha", "list", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.TimedOut {
+		t.Error("list timed out")
+	}
+	if got := strings.Join(result.Lines, "\n"); !strings.Contains(got, "There are 1/10 players online:") || !strings.Contains(got, "Steve") {
+		t.Errorf("lines = %q, want the player list", result.Lines)
+	}
+
+	if _, err := u.RunCommand("beta", "list", 0); err == nil {
+		t.Error("command to an unknown world succeeded")
+	}
+}
+
+func TestConnectDisconnect(t *testing.T) {
+	fake := NewFakeRuntime()
+	u, repo := newTestUC(t, fake, testWorld("alpha", 1))
+	startWorld(t, u, "alpha")
+	p := fake.Process("alpha")
+
+	p.Connect("Steve", "2535400000000001")
+	p.Connect("Alex", "2535400000000002")
+	eventually(t, "both players to join", func() bool {
+		players, _ := u.GetOnlinePlayers("alpha")
+		return len(players) == 2
+	})
+	if got := repo.openSessions(); got != 2 {
+		t.Errorf("open sessions = %d, want 2", got)
+	}
+
+	p.Disconnect("Steve", "2535400000000001")
+	eventually(t, "Steve to leave", func() bool {
+		players, _ := u.GetOnlinePlayers("alpha")
+		return len(players) == 1 && players[0].Name == "Alex"
+	})
+	eventually(t, "the session of Steve to close", func() bool {
+		return repo.openSessions() == 1
+	})
+
+	// stopping the world ends the stay of everyone still online
+	if _, err := u.StopServer("alpha", 0); err != nil {
+		t.Fatal(err)
+	}
+	eventually(t, "every session to close", func() bool {
+		return repo.openSessions() == 0
+	})
+}
@@ -0,0 +1,433 @@
+package usecase
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	logDirName    = "logs"
+	activeLogFile = "server.log"
+
+	// every stored line starts with the time the manager read it
+	logTimeLayout   = "2006-01-02T15:04:05.000Z07:00"
+	rotatedLogStamp = "20060102-150405"
+)
+
+func logDir(name string) string {
+	return filepath.Join("data/servers", name, logDirName)
+}
+
+// worldLog appends the output of one world to logs/server.log and rotates it
+// by size and age. Rotated files are gzipped and pruned after the retention.
+// It is only used from the scanOutput goroutine of the world.
+type worldLog struct {
+	world  string
+	dir    string
+	f      *os.File
+	w      *bufio.Writer
+	size   int64
+	opened time.Time
+
+	maxSize   int64
+	every     time.Duration
+	retention time.Duration
+}
+
+func (u *bedrockUC) openWorldLog(name string) (*worldLog, error) {
+	l := &worldLog{
+		world:     name,
+		dir:       logDir(name),
+		maxSize:   u.logMaxSize,
+		every:     u.logRotateEvery,
+		retention: u.logRetention,
+	}
+	if err := os.MkdirAll(l.dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+	if l.size > 0 && l.due(time.Now()) {
+		l.rotate()
+	}
+	l.prune()
+	return l, nil
+}
+
+func (l *worldLog) open() error {
+	path := filepath.Join(l.dir, activeLogFile)
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	l.f = f
+	l.w = bufio.NewWriter(f)
+	l.size = info.Size()
+	l.opened = time.Now()
+	if l.size > 0 {
+		l.opened = firstLineTime(path, info.ModTime())
+	}
+	return nil
+}
+
+func (l *worldLog) due(now time.Time) bool {
+	return (l.maxSize > 0 && l.size >= l.maxSize) || (l.every > 0 && now.Sub(l.opened) >= l.every)
+}
+
+func (l *worldLog) write(at time.Time, line string) {
+	if l.f == nil {
+		return
+	}
+	if l.size > 0 && l.due(at) {
+		l.rotate()
+		if l.f == nil {
+			return
+		}
+	}
+
+	n, err := fmt.Fprintf(l.w, "%s\t%s\n", at.Format(logTimeLayout), line)
+	l.size += int64(n)
+	if err == nil {
+		// lines are rare enough that every line can reach the disk right away
+		err = l.w.Flush()
+	}
+	if err != nil {
+		log.Printf("server %s: write log failed: %s", l.world, err)
+	}
+}
+
+// rotate renames server.log to a timestamped file, compresses it in the
+// background and starts a new server.log. The counter after the stamp keeps
+// rotations within one second apart and in order by name.
+func (l *worldLog) rotate() {
+	l.w.Flush()
+	l.f.Close()
+	l.f = nil
+
+	active := filepath.Join(l.dir, activeLogFile)
+	stamp := time.Now().Format(rotatedLogStamp)
+	var rotated string
+	for i := 0; ; i++ {
+		rotated = filepath.Join(l.dir, fmt.Sprintf("server-%s-%03d.log", stamp, i))
+		if !fileExists(rotated) && !fileExists(rotated+".gz") {
+			break
+		}
+	}
+
+	if err := os.Rename(active, rotated); err != nil {
+		log.Printf("server %s: rotate log failed: %s", l.world, err)
+	} else {
+		go compressLog(l.world, rotated)
+	}
+
+	if err := l.open(); err != nil {
+		log.Printf("server %s: reopen log failed: %s", l.world, err)
+	}
+	l.prune()
+}
+
+func (l *worldLog) close() {
+	if l.f == nil {
+		return
+	}
+	l.w.Flush()
+	l.f.Close()
+	l.f = nil
+}
+
+// prune removes rotated files older than the retention.
+func (l *worldLog) prune() {
+	if l.retention <= 0 {
+		return
+	}
+	entries, err := os.ReadDir(l.dir)
+	if err != nil {
+		return
+	}
+	cutoff := time.Now().Add(-l.retention)
+	for _, entry := range entries {
+		if entry.Name() == activeLogFile || !isLogFile(entry.Name()) {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.ModTime().After(cutoff) {
+			continue
+		}
+		if err := os.Remove(filepath.Join(l.dir, entry.Name())); err != nil {
+			log.Printf("server %s: prune log failed: %s", l.world, err)
+		}
+	}
+}
+
+func compressLog(world, path string) {
+	src, err := os.Open(path)
+	if err != nil {
+		return
+	}
+	defer src.Close()
+
+	info, err := src.Stat()
+	if err != nil {
+		return
+	}
+
+	tmp := path + ".gz.tmp"
+	dst, err := os.Create(tmp)
+	if err != nil {
+		log.Printf("server %s: compress log failed: %s", world, err)
+		return
+	}
+	zw := gzip.NewWriter(dst)
+	_, err = io.Copy(zw, src)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		log.Printf("server %s: compress log failed: %s", world, err)
+		return
+	}
+
+	// keep the rotation time as mtime, retention and time ranges rely on it
+	os.Chtimes(tmp, info.ModTime(), info.ModTime())
+	if err := os.Rename(tmp, path+".gz"); err != nil {
+		os.Remove(tmp)
+		return
+	}
+	os.Remove(path)
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func isLogFile(name string) bool {
+	return strings.HasPrefix(name, "server") && (strings.HasSuffix(name, ".log") || strings.HasSuffix(name, ".log.gz"))
+}
+
+// parseLogLine splits a stored line into its time and the raw server output.
+func parseLogLine(raw string) (time.Time, string, bool) {
+	stamp, line, ok := strings.Cut(raw, "\t")
+	if !ok {
+		return time.Time{}, raw, false
+	}
+	at, err := time.Parse(logTimeLayout, stamp)
+	if err != nil {
+		return time.Time{}, raw, false
+	}
+	return at, line, true
+}
+
+func firstLineTime(path string, def time.Time) time.Time {
+	f, err := os.Open(path)
+	if err != nil {
+		return def
+	}
+	defer f.Close()
+
+	line, err := bufio.NewReader(f).ReadString('\n')
+	if err != nil && line == "" {
+		return def
+	}
+	if at, _, ok := parseLogLine(strings.TrimRight(line, "\n")); ok {
+		return at
+	}
+	return def
+}
+
+// logFiles lists the log files of a world from oldest to newest.
+func logFiles(name string) ([]os.DirEntry, error) {
+	entries, err := os.ReadDir(logDir(name))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	names := make(map[string]bool, len(entries))
+	for _, entry := range entries {
+		names[entry.Name()] = true
+	}
+
+	files := make([]os.DirEntry, 0, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !isLogFile(entry.Name()) {
+			continue
+		}
+		// compressLog is done with this file but has not removed it yet
+		if names[entry.Name()+".gz"] {
+			continue
+		}
+		files = append(files, entry)
+	}
+	// server-<stamp>-<n>.log(.gz) sorts by time, the active server.log comes last
+	sort.Slice(files, func(i, j int) bool {
+		a, b := files[i].Name(), files[j].Name()
+		if a == activeLogFile || b == activeLogFile {
+			return b == activeLogFile && a != activeLogFile
+		}
+		return a < b
+	})
+	return files, nil
+}
+
+func openLogFile(path string) (io.ReadCloser, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(path, ".gz") {
+		return f, nil
+	}
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{zr, f}, nil
+}
+
+const (
+	defaultLogPage = 200
+	maxLogPage     = 5000
+)
+
+// GetLogHistory searches the stored logs of a world, running or not. Lines
+// come oldest first and are paged after the time range and filters apply.
+func (u *bedrockUC) GetLogHistory(name string, q dto.LogQuery) (*dto.LogPage, error) {
+	if _, err := u.bedRepo.GetWorldByName(name); err != nil {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+
+	if q.Limit <= 0 {
+		q.Limit = defaultLogPage
+	}
+	q.Limit = min(q.Limit, maxLogPage)
+	q.Offset = max(q.Offset, 0)
+
+	var re *regexp.Regexp
+	if q.Regex != "" {
+		var err error
+		if re, err = regexp.Compile(q.Regex); err != nil {
+			return nil, fmt.Errorf("%w: regex: %s", utils.ErrInvalidQuery, err)
+		}
+	}
+	needle := strings.ToLower(q.Query)
+
+	files, err := logFiles(name)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &dto.LogPage{Lines: []dto.LogLine{}, Offset: q.Offset, Limit: q.Limit}
+	for _, file := range files {
+		// a rotated file is last written when it is rotated
+		if info, err := file.Info(); err == nil && !q.From.IsZero() &&
+			file.Name() != activeLogFile && info.ModTime().Before(q.From) {
+			continue
+		}
+
+		rc, err := openLogFile(filepath.Join(logDir(name), file.Name()))
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(rc)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			at, line, ok := parseLogLine(scanner.Text())
+			if !ok {
+				continue
+			}
+			if !q.From.IsZero() && at.Before(q.From) {
+				continue
+			}
+			if !q.To.IsZero() && at.After(q.To) {
+				break
+			}
+			if needle != "" && !strings.Contains(strings.ToLower(line), needle) {
+				continue
+			}
+			if re != nil && !re.MatchString(line) {
+				continue
+			}
+
+			if page.Total >= q.Offset && len(page.Lines) < q.Limit {
+				page.Lines = append(page.Lines, dto.LogLine{Time: at, Line: line})
+			}
+			page.Total++
+		}
+		rc.Close()
+	}
+
+	page.HasMore = q.Offset+len(page.Lines) < page.Total
+	return page, nil
+}
+
+// GetLogFiles lists the stored log files of a world from oldest to newest.
+func (u *bedrockUC) GetLogFiles(name string) ([]dto.LogFile, error) {
+	if _, err := u.bedRepo.GetWorldByName(name); err != nil {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+
+	files, err := logFiles(name)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]dto.LogFile, 0, len(files))
+	for _, file := range files {
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		resp = append(resp, dto.LogFile{
+			Name:       file.Name(),
+			Size:       info.Size(),
+			ModTime:    info.ModTime(),
+			Compressed: strings.HasSuffix(file.Name(), ".gz"),
+		})
+	}
+	return resp, nil
+}
+
+// LogFilePath resolves a file name from GetLogFiles to its path on disk.
+func (u *bedrockUC) LogFilePath(name, file string) (string, error) {
+	if _, err := u.bedRepo.GetWorldByName(name); err != nil {
+		return "", fmt.Errorf("server %s not found", name)
+	}
+	if file != filepath.Base(file) || !isLogFile(file) {
+		return "", fmt.Errorf("%w: %s", utils.ErrLogNotFound, file)
+	}
+
+	path := filepath.Join(logDir(name), file)
+	if !fileExists(path) {
+		return "", fmt.Errorf("%w: %s", utils.ErrLogNotFound, file)
+	}
+	return path, nil
+}
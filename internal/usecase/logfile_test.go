@@ -0,0 +1,173 @@
+package usecase
+
+import (
+	"compress/gzip"
+	"fmt"
+	"minecrat_go/dto"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeLogFile stores lines in the log layout, gzipped for a .gz name, and
+// sets the mtime like a rotation at modTime would.
+func writeLogFile(t *testing.T, world, file string, modTime time.Time, lines ...string) {
+	t.Helper()
+	var b strings.Builder
+	for _, line := range lines {
+		fmt.Fprintf(&b, "%s\t%s\n", modTime.Format(logTimeLayout), line)
+	}
+
+	path := filepath.Join(logDir(world), file)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.HasSuffix(file, ".gz") {
+		zw := gzip.NewWriter(f)
+		zw.Write([]byte(b.String()))
+		zw.Close()
+	} else {
+		f.WriteString(b.String())
+	}
+	if err := f.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func logFileNames(t *testing.T, world string) []string {
+	t.Helper()
+	files, err := logFiles(world)
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0, len(files))
+	for _, file := range files {
+		names = append(names, file.Name())
+	}
+	return names
+}
+
+func TestWorldLogRotatesOnSize(t *testing.T) {
+	u, _ := newTestUC(t, NewFakeRuntime(), testWorld("alpha", 1))
+	u.logMaxSize = 60
+	u.logRotateEvery = 0
+	u.logRetention = 0
+
+	l, err := u.openWorldLog("alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a line with its time is over 30 bytes, every file takes two
+	now := time.Now()
+	for i := 0; i < 6; i++ {
+		l.write(now, fmt.Sprintf("line %d", i))
+	}
+	l.close()
+
+	eventually(t, "the rotated files to be compressed", func() bool {
+		names := logFileNames(t, "alpha")
+		return len(names) == 3 && strings.HasSuffix(names[0], ".gz") && strings.HasSuffix(names[1], ".gz")
+	})
+	names := logFileNames(t, "alpha")
+	if names[2] != activeLogFile {
+		t.Errorf("files = %q, want %s last", names, activeLogFile)
+	}
+	// rotations within one second are told apart by the counter
+	stamp := len("server-") + len(rotatedLogStamp)
+	if names[0][:stamp] == names[1][:stamp] &&
+		(!strings.HasSuffix(names[0], "-000.log.gz") || !strings.HasSuffix(names[1], "-001.log.gz")) {
+		t.Errorf("files = %q, want the counter 000 before 001", names)
+	}
+
+	page, err := u.GetLogHistory("alpha", dto.LogQuery{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, line := range page.Lines {
+		got = append(got, line.Line)
+	}
+	want := []string{"line 0", "line 1", "line 2", "line 3", "line 4", "line 5"}
+	if !slices.Equal(got, want) {
+		t.Errorf("history = %q, want %q", got, want)
+	}
+}
+
+func TestWorldLogPrunesByRetention(t *testing.T) {
+	u, _ := newTestUC(t, NewFakeRuntime(), testWorld("alpha", 1))
+	u.logRetention = 24 * time.Hour
+
+	now := time.Now()
+	writeLogFile(t, "alpha", "server-20260101-000000-000.log.gz", now.Add(-48*time.Hour), "old")
+	writeLogFile(t, "alpha", "server-20260102-000000-000.log.gz", now.Add(-time.Hour), "recent")
+	writeLogFile(t, "alpha", "notes.txt", now.Add(-48*time.Hour), "not a log")
+
+	l, err := u.openWorldLog("alpha")
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.close()
+
+	want := []string{"server-20260102-000000-000.log.gz", activeLogFile}
+	if names := logFileNames(t, "alpha"); !slices.Equal(names, want) {
+		t.Errorf("files = %q, want %q", names, want)
+	}
+	if _, err := os.Stat(filepath.Join(logDir("alpha"), "notes.txt")); err != nil {
+		t.Errorf("a file that is no log was pruned: %s", err)
+	}
+}
+
+func TestGetLogHistoryPages(t *testing.T) {
+	u, _ := newTestUC(t, NewFakeRuntime(), testWorld("alpha", 1))
+
+	base := time.Now().Add(-time.Hour).Truncate(time.Second)
+	writeLogFile(t, "alpha", "server-20260101-000000-000.log.gz", base, "a1", "a2", "a3")
+	writeLogFile(t, "alpha", "server-20260101-000000-001.log.gz", base.Add(time.Minute), "b1", "b2")
+	writeLogFile(t, "alpha", "server-20260101-000001-000.log", base.Add(2*time.Minute), "c1")
+	writeLogFile(t, "alpha", activeLogFile, base.Add(3*time.Minute), "d1", "d2")
+
+	tests := []struct {
+		name    string
+		q       dto.LogQuery
+		want    []string
+		total   int
+		hasMore bool
+	}{
+		{"every file in order", dto.LogQuery{}, []string{"a1", "a2", "a3", "b1", "b2", "c1", "d1", "d2"}, 8, false},
+		{"page across two gzipped files", dto.LogQuery{Offset: 2, Limit: 3}, []string{"a3", "b1", "b2"}, 8, true},
+		{"page into the active file", dto.LogQuery{Offset: 5, Limit: 2}, []string{"c1", "d1"}, 8, true},
+		{"last page", dto.LogQuery{Offset: 6, Limit: 5}, []string{"d1", "d2"}, 8, false},
+		{"offset past the end", dto.LogQuery{Offset: 20}, nil, 8, false},
+		{"filter then page", dto.LogQuery{Query: "B", Offset: 1, Limit: 1}, []string{"b2"}, 2, false},
+		{"time range skips older files", dto.LogQuery{From: base.Add(time.Minute), To: base.Add(2 * time.Minute)}, []string{"b1", "b2", "c1"}, 3, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := u.GetLogHistory("alpha", tt.q)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, line := range page.Lines {
+				got = append(got, line.Line)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("lines = %q, want %q", got, tt.want)
+			}
+			if page.Total != tt.total || page.HasMore != tt.hasMore {
+				t.Errorf("total = %d has more = %v, want %d %v", page.Total, page.HasMore, tt.total, tt.hasMore)
+			}
+		})
+	}
+}
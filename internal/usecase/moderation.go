@@ -0,0 +1,412 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"minecrat_go/internal/repository"
+	"minecrat_go/model"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// actions of the moderation history
+const (
+	ModerationWarn   = "warn"
+	ModerationMute   = "mute"
+	ModerationUnmute = "unmute"
+	ModerationKick   = "kick"
+)
+
+// consoleName quotes a gamertag for a console command, a quote inside it
+// would end the selector early.
+func consoleName(gamertag string) string {
+	return `"` + strings.ReplaceAll(consoleText(gamertag), `"`, "") + `"`
+}
+
+func kickCommand(gamertag, message string) string {
+	if message == "" {
+		return "kick " + consoleName(gamertag)
+	}
+	return fmt.Sprintf("kick %s %s", consoleName(gamertag), consoleText(message))
+}
+
+// muteCommand needs cheats on the world, bedrock_server answers it with
+// "Unknown command" otherwise.
+func muteCommand(gamertag string, muted bool) string {
+	return fmt.Sprintf("ability %s mute %t", consoleName(gamertag), muted)
+}
+
+func muteActive(mute *model.ModerationAction, at time.Time) bool {
+	return mute.LiftedAt == nil && (mute.ExpiresAt == nil || mute.ExpiresAt.After(at))
+}
+
+func toModerationDTO(action *model.ModerationAction, world string, at time.Time) dto.ModerationAction {
+	resp := dto.ModerationAction{
+		ID:        action.ID,
+		Action:    action.Action,
+		Xuid:      action.Xuid,
+		Gamertag:  action.Gamertag,
+		World:     world,
+		Reason:    action.Reason,
+		Actor:     action.Actor,
+		CreatedAt: action.CreatedAt,
+		ExpiresAt: action.ExpiresAt,
+		LiftedAt:  action.LiftedAt,
+		Pending:   action.Pending,
+	}
+	if world == "" && action.WorldServer != nil {
+		resp.World = action.WorldServer.Name
+	}
+	if action.Action == ModerationMute {
+		resp.Active = muteActive(action, at)
+	}
+	return resp
+}
+
+// moderationTarget finds the player an action is about. Players on the
+// roster of the world are matched by xuid or gamertag, anyone else through
+// the player directory.
+func (u *bedrockUC) moderationTarget(world, id string) (onlinePlayer, bool, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return onlinePlayer{}, false, fmt.Errorf("%w: player is required", utils.ErrInvalidAction)
+	}
+
+	u.s.RLock()
+	server, running := u.servers[world]
+	u.s.RUnlock()
+	find := func(match func(onlinePlayer) bool) (onlinePlayer, bool) {
+		if !running {
+			return onlinePlayer{}, false
+		}
+		server.PlayerMu.Lock()
+		defer server.PlayerMu.Unlock()
+		for _, player := range server.Online {
+			if match(player) {
+				return player, true
+			}
+		}
+		return onlinePlayer{}, false
+	}
+
+	if player, ok := find(func(p onlinePlayer) bool {
+		return p.Xuid == id || strings.EqualFold(p.Name, id)
+	}); ok {
+		return player, true, nil
+	}
+
+	target := onlinePlayer{}
+	if player, err := u.resolvePlayer(id); err == nil {
+		target.Xuid, target.Name = player.Xuid, player.Gamertag
+	} else if errors.Is(err, utils.ErrPlayerNotFound) && isXuid(id) {
+		target.Xuid = id
+	} else {
+		return onlinePlayer{}, false, err
+	}
+
+	// the directory knows the account under a name it no longer uses
+	if player, ok := find(func(p onlinePlayer) bool { return p.Xuid == target.Xuid }); ok {
+		return player, true, nil
+	}
+	return target, false, nil
+}
+
+// checkReason refuses a reason that does not fit on one console line.
+func checkReason(req *dto.ModerationReq) error {
+	if hasControl(req.Reason) {
+		return fmt.Errorf("%w: reason must be a single line", utils.ErrInvalidAction)
+	}
+	return nil
+}
+
+func newModeration(worlddb *model.WorldServer, target onlinePlayer, action string, req *dto.ModerationReq) *model.ModerationAction {
+	record := model.ModerationAction{
+		Xuid:          target.Xuid,
+		Gamertag:      target.Name,
+		WorldServerId: worlddb.ID,
+		Action:        action,
+		Reason:        strings.TrimSpace(req.Reason),
+		Actor:         req.Actor,
+		CreatedAt:     time.Now(),
+	}
+	if req.ActorId != 0 {
+		actorId := req.ActorId
+		record.ActorId = &actorId
+	}
+	return &record
+}
+
+func (u *bedrockUC) saveModeration(world string, record *model.ModerationAction) (*dto.ModerationAction, error) {
+	if err := u.bedRepo.CreateModeration(record); err != nil {
+		return nil, err
+	}
+	log.Printf("server %s: %s %s by %s", world, record.Action, record.Gamertag, record.Actor)
+	resp := toModerationDTO(record, world, time.Now())
+	return &resp, nil
+}
+
+// WarnPlayer sends an online player a private warning.
+func (u *bedrockUC) WarnPlayer(name string, req *dto.ModerationReq) (*dto.ModerationAction, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		return nil, fmt.Errorf("%w: a warning needs a reason", utils.ErrInvalidAction)
+	}
+	if err := checkReason(req); err != nil {
+		return nil, err
+	}
+
+	target, online, err := u.moderationTarget(name, req.Player)
+	if err != nil {
+		return nil, err
+	}
+	if !online {
+		return nil, fmt.Errorf("%w: %s on %s", utils.ErrPlayerOffline, req.Player, name)
+	}
+
+	record := newModeration(worlddb, target, ModerationWarn, req)
+	// tell prints nothing on the console, the roster already says the player is there
+	tell := fmt.Sprintf("tell %s Warning: %s", consoleName(target.Name), consoleText(record.Reason))
+	if err := u.SendCommandforAPI(name, tell); err != nil {
+		return nil, err
+	}
+	return u.saveModeration(name, record)
+}
+
+// KickPlayer kicks an online player, the reason is shown to the player.
+func (u *bedrockUC) KickPlayer(name string, req *dto.ModerationReq) (*dto.ModerationAction, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+	if err := checkReason(req); err != nil {
+		return nil, err
+	}
+
+	target, online, err := u.moderationTarget(name, req.Player)
+	if err != nil {
+		return nil, err
+	}
+	if !online {
+		return nil, fmt.Errorf("%w: %s on %s", utils.ErrPlayerOffline, req.Player, name)
+	}
+
+	record := newModeration(worlddb, target, ModerationKick, req)
+	if err := u.checkedCommand(name, kickCommand(target.Name, record.Reason)); err != nil {
+		return nil, err
+	}
+	return u.saveModeration(name, record)
+}
+
+// MutePlayer mutes a player on a world, until the duration passes or an
+// unmute. A player that is offline gets the mute when it joins next.
+func (u *bedrockUC) MutePlayer(name string, req *dto.ModerationReq) (*dto.ModerationAction, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+	if err := checkReason(req); err != nil {
+		return nil, err
+	}
+	if !worlddb.AllowCheat {
+		return nil, fmt.Errorf("%w: muting needs cheats enabled on %s", utils.ErrInvalidAction, name)
+	}
+
+	target, online, err := u.moderationTarget(name, req.Player)
+	if err != nil {
+		return nil, err
+	}
+	if target.Xuid == "" {
+		return nil, fmt.Errorf("%w: %s has no xuid", utils.ErrInvalidAction, target.Name)
+	}
+
+	record := newModeration(worlddb, target, ModerationMute, req)
+	if req.Duration != "" {
+		d, err := parseDuration(req.Duration)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", utils.ErrInvalidAction, err)
+		}
+		expires := record.CreatedAt.Add(d)
+		record.ExpiresAt = &expires
+	}
+
+	if online {
+		if err := u.checkedCommand(name, muteCommand(target.Name, true)); err != nil {
+			return nil, err
+		}
+	}
+	record.Pending = !online
+
+	// the new mute replaces the running one, the player stays muted
+	if prev, err := u.bedRepo.GetActiveMute(target.Xuid, worlddb.ID, record.CreatedAt); err == nil {
+		if err := u.bedRepo.LiftMute(prev.ID, record.CreatedAt, false); err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, err
+		}
+	} else if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	return u.saveModeration(name, record)
+}
+
+// UnmutePlayer ends the running mute of a player on a world.
+func (u *bedrockUC) UnmutePlayer(name string, req *dto.ModerationReq) (*dto.ModerationAction, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+
+	target, online, err := u.moderationTarget(name, req.Player)
+	if err != nil {
+		return nil, err
+	}
+
+	record := newModeration(worlddb, target, ModerationUnmute, req)
+	mute, err := u.bedRepo.GetActiveMute(target.Xuid, worlddb.ID, record.CreatedAt)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s is not muted on %s", utils.ErrInvalidAction, req.Player, name)
+		}
+		return nil, err
+	}
+
+	if online {
+		if err := u.checkedCommand(name, muteCommand(target.Name, false)); err != nil {
+			return nil, err
+		}
+	}
+	if err := u.bedRepo.LiftMute(mute.ID, record.CreatedAt, !online); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s is not muted on %s", utils.ErrInvalidAction, req.Player, name)
+		}
+		return nil, err
+	}
+	return u.saveModeration(name, record)
+}
+
+// onModerationEvent hands a spawning player the mutes and unmutes it missed
+// while it was offline.
+func (u *bedrockUC) onModerationEvent(ev ServerEvent) {
+	if ev.Kind != KindPlayerSpawned || ev.Xuid == "" {
+		return
+	}
+	// the ability command goes through the console, which must not wait on this scanner
+	go u.applyPendingMutes(ev.World, ev.server.Id, ev.Xuid, ev.Player)
+}
+
+func (u *bedrockUC) applyPendingMutes(world string, worldId uint, xuid, gamertag string) {
+	mutes, err := u.bedRepo.GetPendingMutes(xuid, worldId)
+	if err != nil {
+		log.Printf("server %s: mute check of %s failed: %s", world, gamertag, err)
+		return
+	}
+
+	for i := range mutes {
+		mute := &mutes[i]
+		if err := u.checkedCommand(world, muteCommand(gamertag, muteActive(mute, time.Now()))); err != nil {
+			log.Printf("server %s: apply mute %d to %s failed: %s", world, mute.ID, gamertag, err)
+			return
+		}
+		if err := u.bedRepo.SetModerationPending(mute.ID, false); err != nil {
+			log.Printf("server %s: save mute %d failed: %s", world, mute.ID, err)
+		}
+	}
+}
+
+// expireMutes lifts the timed mutes whose time is up, it runs every minute
+// from the cron runner.
+func (u *bedrockUC) expireMutes() {
+	mutes, err := u.bedRepo.GetExpiredMutes(time.Now())
+	if err != nil {
+		log.Printf("expire mutes failed: %s", err)
+		return
+	}
+
+	for i := range mutes {
+		mute := &mutes[i]
+		if mute.WorldServer == nil {
+			continue
+		}
+		world := mute.WorldServer.Name
+
+		target, online, err := u.moderationTarget(world, mute.Xuid)
+		if err != nil {
+			target, online = onlinePlayer{Xuid: mute.Xuid, Name: mute.Gamertag}, false
+		}
+		if online {
+			if err := u.checkedCommand(world, muteCommand(target.Name, false)); err != nil {
+				log.Printf("server %s: unmute %s failed: %s", world, target.Name, err)
+				online = false
+			}
+		}
+		if err := u.bedRepo.LiftMute(mute.ID, *mute.ExpiresAt, !online); err != nil {
+			if !errors.Is(err, gorm.ErrRecordNotFound) {
+				log.Printf("mute %d: expire failed: %s", mute.ID, err)
+			}
+			continue
+		}
+
+		record := newModeration(mute.WorldServer, target, ModerationUnmute, &dto.ModerationReq{Reason: "expired", Actor: systemActor})
+		if _, err := u.saveModeration(world, record); err != nil {
+			log.Printf("mute %d: save unmute failed: %s", mute.ID, err)
+		}
+	}
+}
+
+// GetModerationHistory returns everything done to a player, on one world or
+// on all of them, together with its bans.
+func (u *bedrockUC) GetModerationHistory(player, world string) (*dto.ModerationHistory, error) {
+	resp := &dto.ModerationHistory{Muted: []string{}, Actions: []dto.ModerationAction{}, Bans: []dto.Ban{}}
+	if p, err := u.resolvePlayer(player); err == nil {
+		resp.Xuid, resp.Gamertag = p.Xuid, p.Gamertag
+	} else if errors.Is(err, utils.ErrPlayerNotFound) && isXuid(strings.TrimSpace(player)) {
+		resp.Xuid = strings.TrimSpace(player)
+	} else {
+		return nil, err
+	}
+
+	filter := repository.BanFilter{Xuid: resp.Xuid}
+	if world != "" {
+		worlddb, err := u.bedRepo.GetWorldByName(world)
+		if err != nil {
+			return nil, fmt.Errorf("server %s not found", world)
+		}
+		filter.WorldId = worlddb.ID
+	}
+
+	actions, err := u.bedRepo.GetModerationHistory(resp.Xuid, filter.WorldId)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	for i := range actions {
+		action := toModerationDTO(&actions[i], "", now)
+		switch action.Action {
+		case ModerationWarn:
+			resp.Warnings++
+		case ModerationMute:
+			resp.Mutes++
+			if action.Active {
+				resp.Muted = append(resp.Muted, action.World)
+			}
+		case ModerationKick:
+			resp.Kicks++
+		}
+		resp.Actions = append(resp.Actions, action)
+	}
+
+	bans, err := u.bedRepo.GetBans(filter)
+	if err != nil {
+		return nil, err
+	}
+	for i := range bans {
+		resp.Bans = append(resp.Bans, toBanDTO(&bans[i], "", now))
+	}
+	return resp, nil
+}
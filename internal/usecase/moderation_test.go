@@ -0,0 +1,57 @@
+package usecase
+
+import (
+	"errors"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"slices"
+	"testing"
+)
+
+func TestMuteCommand(t *testing.T) {
+	tests := []struct {
+		gamertag string
+		muted    bool
+		want     string
+	}{
+		{"Steve", true, `ability "Steve" mute true`},
+		{"Steve Jobs", false, `ability "Steve Jobs" mute false`},
+		{"Steve\nstop", true, `ability "Steve stop" mute true`},
+		{`Steve" mute false`, true, `ability "Steve mute false" mute true`},
+	}
+	for _, tt := range tests {
+		if got := muteCommand(tt.gamertag, tt.muted); got != tt.want {
+			t.Errorf("muteCommand(%q, %v) = %q, want %q", tt.gamertag, tt.muted, got, tt.want)
+		}
+	}
+}
+
+func TestModerationRejectsMultilineReason(t *testing.T) {
+	fake := NewFakeRuntime()
+	world := testWorld("alpha", 1)
+	world.AllowCheat = true
+	u, _ := newTestUC(t, fake, world)
+	startWorld(t, u, "alpha")
+	joinPlayer(t, u, fake, "alpha", "Steve", "2535400000000001")
+
+	actions := map[string]func(string, *dto.ModerationReq) (*dto.ModerationAction, error){
+		"warn": u.WarnPlayer,
+		"kick": u.KickPlayer,
+		"mute": u.MutePlayer,
+	}
+	for name, action := range actions {
+		for _, reason := range []string{"spam\nstop", "spam\rstop", "spam\x00"} {
+			_, err := action("alpha", &dto.ModerationReq{Player: "Steve", Reason: reason})
+			if !errors.Is(err, utils.ErrInvalidAction) {
+				t.Errorf("%s with reason %q: err = %v, want ErrInvalidAction", name, reason, err)
+			}
+		}
+	}
+
+	if _, err := u.WarnPlayer("alpha", &dto.ModerationReq{Player: "Steve", Reason: "no spam"}); err != nil {
+		t.Fatalf("warn: %s", err)
+	}
+	if commands := sentCommands(t, u, fake, "alpha"); slices.Contains(commands, "stop") {
+		t.Errorf("console got %q, a reason ran as a command", commands)
+	}
+}
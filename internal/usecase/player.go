@@ -0,0 +1,195 @@
+package usecase
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"minecrat_go/model"
+	"sort"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultPlayerPage = 50
+	maxPlayerPage     = 500
+)
+
+// recordPlayer keeps the global directory up to date from connect lines.
+func (u *bedrockUC) recordPlayer(ev ServerEvent) {
+	if ev.Xuid == "" {
+		// offline-mode servers print no xuid
+		return
+	}
+	if err := u.bedRepo.UpsertPlayer(ev.Xuid, ev.Player, ev.Time); err != nil {
+		log.Printf("server %s: save player %s in directory failed: %s", ev.World, ev.Player, err)
+	}
+}
+
+// resolvePlayer finds a player by xuid, by current gamertag, or by a gamertag
+// it used before, in that order.
+func (u *bedrockUC) resolvePlayer(id string) (*model.Player, error) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		return nil, fmt.Errorf("%w: empty player", utils.ErrPlayerNotFound)
+	}
+
+	player, err := u.bedRepo.GetPlayer(id)
+	if err == nil {
+		return player, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	for _, lookup := range []func(string) ([]model.Player, error){
+		u.bedRepo.GetPlayersByGamertag,
+		u.bedRepo.GetPlayersByPastGamertag,
+	} {
+		players, err := lookup(id)
+		if err != nil {
+			return nil, err
+		}
+		switch len(players) {
+		case 0:
+			continue
+		case 1:
+			return &players[0], nil
+		default:
+			xuids := make([]string, 0, len(players))
+			for _, p := range players {
+				xuids = append(xuids, p.Xuid)
+			}
+			return nil, fmt.Errorf("%w: %s is used by xuid %s", utils.ErrAmbiguousPlayer, id, strings.Join(xuids, ", "))
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", utils.ErrPlayerNotFound, id)
+}
+
+// isXuid tells whether id has the shape of an xuid, gamertags cannot start
+// with a digit.
+func isXuid(id string) bool {
+	if id == "" {
+		return false
+	}
+	for _, r := range id {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// playerXuid resolves id to an xuid. An xuid that never connected is taken
+// as is, so permissions can be prepared before the first join.
+func (u *bedrockUC) playerXuid(id string) (string, error) {
+	player, err := u.resolvePlayer(id)
+	if err == nil {
+		return player.Xuid, nil
+	}
+	if errors.Is(err, utils.ErrPlayerNotFound) && isXuid(strings.TrimSpace(id)) {
+		return strings.TrimSpace(id), nil
+	}
+	return "", err
+}
+
+// playerGamertag resolves id to the gamertag console commands expect. A name
+// the directory does not know is used as is.
+func (u *bedrockUC) playerGamertag(id string) (string, error) {
+	player, err := u.resolvePlayer(id)
+	if err == nil {
+		return player.Gamertag, nil
+	}
+	if errors.Is(err, utils.ErrPlayerNotFound) && !isXuid(strings.TrimSpace(id)) {
+		return strings.TrimSpace(id), nil
+	}
+	return "", err
+}
+
+// onlineWorlds maps every connected xuid to the worlds it is on.
+func (u *bedrockUC) onlineWorlds() map[string][]string {
+	u.s.RLock()
+	servers := make(map[string]*BedrockServer, len(u.servers))
+	for name, server := range u.servers {
+		servers[name] = server
+	}
+	u.s.RUnlock()
+
+	online := make(map[string][]string)
+	for name, server := range servers {
+		server.PlayerMu.Lock()
+		for xuid := range server.Online {
+			online[xuid] = append(online[xuid], name)
+		}
+		server.PlayerMu.Unlock()
+	}
+	for xuid := range online {
+		sort.Strings(online[xuid])
+	}
+	return online
+}
+
+func toPlayerProfile(player *model.Player, online map[string][]string) dto.PlayerProfile {
+	profile := dto.PlayerProfile{
+		Xuid:      player.Xuid,
+		Gamertag:  player.Gamertag,
+		FirstSeen: player.FirstSeen,
+		LastSeen:  player.LastSeen,
+		Online:    online[player.Xuid],
+	}
+	if profile.Online == nil {
+		profile.Online = []string{}
+	}
+	for _, name := range player.Names {
+		profile.Names = append(profile.Names, dto.GamertagUse{
+			Gamertag:  name.Gamertag,
+			FirstSeen: name.FirstSeen,
+			LastSeen:  name.LastSeen,
+		})
+	}
+	return profile
+}
+
+func (u *bedrockUC) GetPlayers(query string, limit, offset int) (*dto.PlayerPage, error) {
+	if limit <= 0 {
+		limit = defaultPlayerPage
+	}
+	limit = min(limit, maxPlayerPage)
+	offset = max(offset, 0)
+
+	players, total, err := u.bedRepo.SearchPlayers(strings.TrimSpace(query), limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	online := u.onlineWorlds()
+	page := &dto.PlayerPage{Players: make([]dto.PlayerProfile, 0, len(players)), Total: total, Offset: offset, Limit: limit}
+	for i := range players {
+		page.Players = append(page.Players, toPlayerProfile(&players[i], online))
+	}
+	return page, nil
+}
+
+// GetPlayer returns a player with its name history, id is an xuid or a
+// gamertag.
+func (u *bedrockUC) GetPlayer(id string) (*dto.PlayerProfile, error) {
+	player, err := u.resolvePlayer(id)
+	if err != nil {
+		return nil, err
+	}
+	// the gamertag lookups do not load the history
+	if player.Names == nil {
+		if player, err = u.bedRepo.GetPlayer(player.Xuid); err != nil {
+			return nil, err
+		}
+	}
+
+	profile := toPlayerProfile(player, u.onlineWorlds())
+	if profile.Access, err = u.playerAccess(player.Xuid); err != nil {
+		return nil, err
+	}
+	return &profile, nil
+}
@@ -0,0 +1,256 @@
+package usecase
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// portRange is the range the allocator hands out ports from, set with
+// BEDROCK_PORT_RANGE as "first-last".
+type portRange struct {
+	first int
+	last  int
+}
+
+func parsePortRange(value string) portRange {
+	def := portRange{first: 19132, last: 19232}
+
+	first, last, ok := strings.Cut(value, "-")
+	if !ok {
+		return def
+	}
+	from, err1 := strconv.Atoi(strings.TrimSpace(first))
+	to, err2 := strconv.Atoi(strings.TrimSpace(last))
+	if err1 != nil || err2 != nil || from < 1 || to > 65535 || from >= to {
+		return def
+	}
+	return portRange{first: from, last: to}
+}
+
+// assignPorts checks the ports of a world against every other world and fills
+// in the ones the client left out. worldId is the world being edited, 0 on
+// create. The caller must hold portMu.
+func (u *bedrockUC) assignPorts(req *dto.ServerParams, worldId uint, allocate bool) error {
+	worlds, err := u.bedRepo.GetWorldPorts()
+	if err != nil {
+		return err
+	}
+
+	owners := make(map[int]string)
+	for _, world := range worlds {
+		if world.ID == worldId {
+			continue
+		}
+		owners[world.Port] = world.Name
+		if world.PortV6 != 0 {
+			owners[world.PortV6] = world.Name
+		}
+	}
+
+	for _, port := range []int{req.Port, req.PortV6} {
+		if owner, ok := owners[port]; ok && port != 0 {
+			return fmt.Errorf("%w: port %d is assigned to world %s", utils.ErrPortInUse, port, owner)
+		}
+	}
+	if !allocate {
+		return nil
+	}
+
+	next := func(skip int) (int, error) {
+		for port := u.ports.first; port <= u.ports.last; port++ {
+			if _, ok := owners[port]; ok || port == skip {
+				continue
+			}
+			if u.hooks.portHolder(port) != "" {
+				continue
+			}
+			return port, nil
+		}
+		return 0, fmt.Errorf("%w: no free port left in %d-%d", utils.ErrPortInUse, u.ports.first, u.ports.last)
+	}
+
+	if req.Port == 0 {
+		if req.Port, err = next(req.PortV6); err != nil {
+			return err
+		}
+	}
+	if req.PortV6 == 0 {
+		if req.PortV6, err = next(req.Port); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkPorts probes the UDP ports of a world right before it is launched.
+func (u *bedrockUC) checkPorts(name string, ports ...int) error {
+	for _, port := range ports {
+		if port == 0 {
+			continue
+		}
+		if holder := u.hooks.portHolder(port); holder != "" {
+			return fmt.Errorf("%w: cannot start server %s, udp port %d is held by %s", utils.ErrPortInUse, name, port, holder)
+		}
+	}
+	return nil
+}
+
+// portHolder returns who holds a UDP port on the host, or "" if it is free.
+func (u *bedrockUC) portHolder(port int) string {
+	if udpPortFree(port) {
+		return ""
+	}
+
+	pid := udpSocketOwner(port)
+	if pid == 0 {
+		return "another process"
+	}
+
+	if world, ok := ownerOf(pid, u.worldPids(), parentPid); ok {
+		return fmt.Sprintf("world %s (pid %d)", world, pid)
+	}
+
+	comm, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return fmt.Sprintf("pid %d", pid)
+	}
+	return fmt.Sprintf("%s (pid %d)", strings.TrimSpace(string(comm)), pid)
+}
+
+// portForwarder is a server process whose game ports are bound on the host
+// by helper processes, like slirp4netns of a sandbox or the conmon of a
+// container.
+type portForwarder interface {
+	forwarderPids() []int
+}
+
+// worldPids maps the pids of every running world and of its port forwarders
+// to the world.
+// The forwarders may have to be asked for, so that happens on a copy of the
+// servers outside the lock.
+func (u *bedrockUC) worldPids() map[int]string {
+	u.s.RLock()
+	procs := make(map[string]ServerProcess, len(u.servers))
+	for world, server := range u.servers {
+		procs[world] = server.Proc
+	}
+	u.s.RUnlock()
+
+	pids := make(map[int]string)
+	for world, proc := range procs {
+		pids[proc.Pid()] = world
+		if fwd, ok := proc.(portForwarder); ok {
+			for _, pid := range fwd.forwarderPids() {
+				pids[pid] = world
+			}
+		}
+	}
+	return pids
+}
+
+// ownerOf walks from pid up its parents to the first pid in owners.
+func ownerOf(pid int, owners map[int]string, parent func(pid int) int) (string, bool) {
+	for depth := 0; pid > 1 && depth < 64; depth++ {
+		if world, ok := owners[pid]; ok {
+			return world, true
+		}
+		pid = parent(pid)
+	}
+	return "", false
+}
+
+// parentPid reads the parent of pid from /proc, 0 when it is gone.
+func parentPid(pid int) int {
+	stat, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "stat"))
+	if err != nil {
+		return 0
+	}
+	// the command name in parentheses may contain spaces
+	i := strings.LastIndexByte(string(stat), ')')
+	if i < 0 {
+		return 0
+	}
+	fields := strings.Fields(string(stat[i+1:]))
+	if len(fields) < 2 {
+		return 0
+	}
+	ppid, _ := strconv.Atoi(fields[1])
+	return ppid
+}
+
+func udpPortFree(port int) bool {
+	for _, network := range []string{"udp4", "udp6"} {
+		conn, err := net.ListenPacket(network, net.JoinHostPort("", strconv.Itoa(port)))
+		if err != nil {
+			// a host without ipv6 cannot have the port taken on it
+			if errors.Is(err, syscall.EADDRINUSE) || errors.Is(err, syscall.EACCES) {
+				return false
+			}
+			continue
+		}
+		conn.Close()
+	}
+	return true
+}
+
+// udpSocketOwner finds the pid behind a bound UDP port through the socket
+// inodes in /proc/net/udp{,6}, 0 when it cannot be found.
+func udpSocketOwner(port int) int {
+	inodes := make(map[string]bool)
+	for _, table := range []string{"/proc/net/udp", "/proc/net/udp6"} {
+		f, err := os.Open(table)
+		if err != nil {
+			continue
+		}
+		scanner := bufio.NewScanner(f)
+		scanner.Scan() // header
+		for scanner.Scan() {
+			fields := strings.Fields(scanner.Text())
+			if len(fields) < 10 {
+				continue
+			}
+			_, hexPort, ok := strings.Cut(fields[1], ":")
+			if !ok {
+				continue
+			}
+			if p, err := strconv.ParseInt(hexPort, 16, 32); err == nil && int(p) == port {
+				inodes["socket:["+fields[9]+"]"] = true
+			}
+		}
+		f.Close()
+	}
+	if len(inodes) == 0 {
+		return 0
+	}
+
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0
+	}
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil {
+			continue
+		}
+		fdDir := filepath.Join("/proc", proc.Name(), "fd")
+		fds, err := os.ReadDir(fdDir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			if link, err := os.Readlink(filepath.Join(fdDir, fd.Name())); err == nil && inodes[link] {
+				return pid
+			}
+		}
+	}
+	return 0
+}
@@ -0,0 +1,160 @@
+package usecase
+
+import (
+	"errors"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"testing"
+)
+
+func TestParsePortRange(t *testing.T) {
+	def := portRange{first: 19132, last: 19232}
+	tests := []struct {
+		value string
+		want  portRange
+	}{
+		{"", def},
+		{"20000-20100", portRange{first: 20000, last: 20100}},
+		{" 20000 - 20100 ", portRange{first: 20000, last: 20100}},
+		{"20000", def},
+		{"20100-20000", def},
+		{"20000-20000", def},
+		{"0-100", def},
+		{"60000-70000", def},
+		{"a-b", def},
+	}
+
+	for _, tt := range tests {
+		if got := parsePortRange(tt.value); got != tt.want {
+			t.Errorf("parsePortRange(%q) = %+v, want %+v", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestAssignPorts(t *testing.T) {
+	alpha := testWorld("alpha", 1)
+	alpha.Port, alpha.PortV6 = 20000, 20001
+
+	tests := []struct {
+		name     string
+		req      dto.ServerParams
+		worldId  uint
+		allocate bool
+		held     map[int]bool
+		wantV4   int
+		wantV6   int
+		wantErr  bool
+	}{
+		{
+			name:     "allocates a pair after the taken ports",
+			allocate: true,
+			wantV4:   20002,
+			wantV6:   20003,
+		},
+		{
+			name:     "skips ports held on the host",
+			allocate: true,
+			held:     map[int]bool{20002: true},
+			wantV4:   20003,
+			wantV6:   20004,
+		},
+		{
+			name:     "fills in the missing ipv6 port",
+			req:      dto.ServerParams{Port: 20003},
+			allocate: true,
+			wantV4:   20003,
+			wantV6:   20002,
+		},
+		{
+			name:     "fills in the missing ipv4 port",
+			req:      dto.ServerParams{PortV6: 20002},
+			allocate: true,
+			wantV4:   20003,
+			wantV6:   20002,
+		},
+		{
+			name:    "rejects the ipv4 port of another world",
+			req:     dto.ServerParams{Port: 20000, PortV6: 20004},
+			wantErr: true,
+		},
+		{
+			name:    "rejects the ipv6 port of another world",
+			req:     dto.ServerParams{Port: 20004, PortV6: 20001},
+			wantErr: true,
+		},
+		{
+			name:    "a world keeps its own ports on edit",
+			req:     dto.ServerParams{Port: 20000, PortV6: 20001},
+			worldId: alpha.ID,
+			wantV4:  20000,
+			wantV6:  20001,
+		},
+		{
+			name:     "range exhausted",
+			allocate: true,
+			held:     map[int]bool{20002: true, 20003: true, 20004: true, 20005: true},
+			wantErr:  true,
+		},
+		{
+			name:     "no room left for the ipv6 port",
+			allocate: true,
+			held:     map[int]bool{20002: true, 20003: true, 20004: true},
+			wantErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := newTestUC(t, NewFakeRuntime(), alpha)
+			u.ports = portRange{first: 20000, last: 20005}
+			u.hooks.portHolder = func(port int) string {
+				if tt.held[port] {
+					return "another process"
+				}
+				return ""
+			}
+
+			req := tt.req
+			err := u.assignPorts(&req, tt.worldId, tt.allocate)
+			if tt.wantErr {
+				if !errors.Is(err, utils.ErrPortInUse) {
+					t.Fatalf("err = %v, want ErrPortInUse", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if req.Port != tt.wantV4 || req.PortV6 != tt.wantV6 {
+				t.Errorf("ports = %d/%d, want %d/%d", req.Port, req.PortV6, tt.wantV4, tt.wantV6)
+			}
+		})
+	}
+}
+
+func TestOwnerOf(t *testing.T) {
+	// 300 is slirp4netns of alpha, 410 a helper forked below the conmon of
+	// beta, 500 belongs to no world
+	parents := map[int]int{300: 1, 410: 400, 400: 1, 500: 1, 20: 10}
+	parent := func(pid int) int { return parents[pid] }
+	owners := map[int]string{100: "alpha", 300: "alpha", 400: "beta", 10: "gamma"}
+
+	tests := []struct {
+		pid   int
+		world string
+		ok    bool
+	}{
+		{100, "alpha", true},
+		{300, "alpha", true},
+		{410, "beta", true},
+		{20, "gamma", true},
+		{500, "", false},
+		{1, "", false},
+	}
+	for _, tt := range tests {
+		world, ok := ownerOf(tt.pid, owners, parent)
+		if world != tt.world || ok != tt.ok {
+			t.Errorf("ownerOf(%d) = %q, %v, want %q, %v", tt.pid, world, ok, tt.world, tt.ok)
+		}
+	}
+}
@@ -0,0 +1,417 @@
+package usecase
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	propBool   = "bool"
+	propInt    = "int"
+	propFloat  = "float"
+	propString = "string"
+	propEnum   = "enum"
+)
+
+func limit(v float64) *float64 { return &v }
+
+// propertySchema lists the keys bedrock_server reads from server.properties.
+var propertySchema = []dto.PropertySpec{
+	{Key: "server-name", Type: propString, Default: "Dedicated Server"},
+	{Key: "gamemode", Type: propEnum, Default: "survival", Enum: []string{"survival", "creative", "adventure"}},
+	{Key: "force-gamemode", Type: propBool, Default: "false"},
+	{Key: "difficulty", Type: propEnum, Default: "easy", Enum: []string{"peaceful", "easy", "normal", "hard"}},
+	{Key: "allow-cheats", Type: propBool, Default: "false"},
+	{Key: "max-players", Type: propInt, Default: "10", Min: limit(1)},
+	{Key: "online-mode", Type: propBool, Default: "true"},
+	{Key: "allow-list", Type: propBool, Default: "false"},
+	{Key: "server-port", Type: propInt, Default: "19132", Min: limit(1), Max: limit(65535), Managed: true},
+	{Key: "server-portv6", Type: propInt, Default: "19133", Min: limit(1), Max: limit(65535), Managed: true},
+	{Key: "enable-lan-visibility", Type: propBool, Default: "true"},
+	{Key: "view-distance", Type: propInt, Default: "32", Min: limit(5)},
+	{Key: "tick-distance", Type: propInt, Default: "4", Min: limit(4), Max: limit(12)},
+	{Key: "player-idle-timeout", Type: propInt, Default: "30", Min: limit(0)},
+	{Key: "max-threads", Type: propInt, Default: "8", Min: limit(0)},
+	{Key: "level-name", Type: propString, Default: "Bedrock level", Managed: true},
+	{Key: "level-seed", Type: propString, Default: ""},
+	{Key: "default-player-permission-level", Type: propEnum, Default: "member", Enum: []string{"visitor", "member", "operator"}},
+	{Key: "texturepack-required", Type: propBool, Default: "false"},
+	{Key: "content-log-file-enabled", Type: propBool, Default: "false"},
+	{Key: "content-log-level", Type: propEnum, Default: "info", Enum: []string{"verbose", "info", "warning", "error"}},
+	{Key: "content-log-console-output-enabled", Type: propBool, Default: "false"},
+	{Key: "compression-threshold", Type: propInt, Default: "1", Min: limit(0), Max: limit(65535)},
+	{Key: "compression-algorithm", Type: propEnum, Default: "zlib", Enum: []string{"zlib", "snappy"}},
+	{Key: "server-authoritative-movement", Type: propEnum, Default: "server-auth", Enum: []string{"client-auth", "server-auth", "server-auth-with-rewind"}},
+	{Key: "player-movement-score-threshold", Type: propInt, Default: "20", Min: limit(0)},
+	{Key: "player-movement-action-direction-threshold", Type: propFloat, Default: "0.85", Min: limit(0), Max: limit(1)},
+	{Key: "player-movement-distance-threshold", Type: propFloat, Default: "0.3", Min: limit(0)},
+	{Key: "player-movement-duration-threshold-in-ms", Type: propInt, Default: "500", Min: limit(0)},
+	{Key: "player-position-acceptance-threshold", Type: propFloat, Default: "0.5", Min: limit(0)},
+	{Key: "correct-player-movement", Type: propBool, Default: "false"},
+	{Key: "server-authoritative-block-breaking", Type: propBool, Default: "false"},
+	{Key: "server-authoritative-block-breaking-pick-range-scalar", Type: propFloat, Default: "1.5", Min: limit(0)},
+	{Key: "chat-restriction", Type: propEnum, Default: "None", Enum: []string{"None", "Dropped", "Disabled"}},
+	{Key: "disable-player-interaction", Type: propBool, Default: "false"},
+	{Key: "client-side-chunk-generation-enabled", Type: propBool, Default: "true"},
+	{Key: "block-network-ids-are-hashes", Type: propBool, Default: "true"},
+	{Key: "disable-persona", Type: propBool, Default: "false"},
+	{Key: "disable-custom-skins", Type: propBool, Default: "false"},
+	{Key: "server-build-radius-ratio", Type: propFloat, Default: "Disabled", Enum: []string{"Disabled"}, Min: limit(0), Max: limit(1)},
+	{Key: "allow-outbound-script-debugging", Type: propBool, Default: "false"},
+	{Key: "allow-inbound-script-debugging", Type: propBool, Default: "false"},
+	{Key: "script-debugger-auto-attach", Type: propEnum, Default: "disabled", Enum: []string{"disabled", "connect", "listen"}},
+	{Key: "emit-server-telemetry", Type: propBool, Default: "false"},
+}
+
+var propertySpecs = func() map[string]dto.PropertySpec {
+	specs := make(map[string]dto.PropertySpec, len(propertySchema))
+	for _, spec := range propertySchema {
+		specs[spec.Key] = spec
+	}
+	return specs
+}()
+
+// worldColumns maps the keys the world row keeps a copy of to their column.
+var worldColumns = map[string]string{
+	"gamemode":                        "game_mode",
+	"difficulty":                      "difficult",
+	"allow-cheats":                    "allow_cheat",
+	"max-players":                     "max_player",
+	"view-distance":                   "view_distance",
+	"level-seed":                      "seed_world",
+	"default-player-permission-level": "default_permission_player",
+}
+
+// propertyLine is one line of server.properties, comments and blank lines
+// have no key and are written back untouched.
+type propertyLine struct {
+	key   string
+	value string
+	raw   string
+}
+
+// properties is a parsed server.properties that writes back with the
+// comments, order and line endings it was read with.
+type properties struct {
+	lines []propertyLine
+	eol   string
+	// trailing is set when the file ended with a line break
+	trailing bool
+}
+
+func parseProperties(data []byte) *properties {
+	p := &properties{eol: "\n"}
+	if bytes.Contains(data, []byte("\r\n")) {
+		p.eol = "\r\n"
+	}
+	text := strings.ReplaceAll(string(data), "\r\n", "\n")
+	if text == "" {
+		return p
+	}
+	text, p.trailing = strings.CutSuffix(text, "\n")
+
+	for _, raw := range strings.Split(text, "\n") {
+		line := propertyLine{raw: raw}
+		trimmed := strings.TrimSpace(raw)
+		if trimmed != "" && !strings.HasPrefix(trimmed, "#") {
+			if key, value, ok := strings.Cut(trimmed, "="); ok {
+				line.key, line.value = strings.TrimSpace(key), strings.TrimSpace(value)
+			}
+		}
+		p.lines = append(p.lines, line)
+	}
+	return p
+}
+
+func (p *properties) bytes() []byte {
+	var b strings.Builder
+	for i, line := range p.lines {
+		if i > 0 {
+			b.WriteString(p.eol)
+		}
+		b.WriteString(line.raw)
+	}
+	if p.trailing || len(p.lines) == 0 {
+		b.WriteString(p.eol)
+	}
+	return []byte(b.String())
+}
+
+func (p *properties) get(key string) (string, bool) {
+	for _, line := range p.lines {
+		if line.key == key {
+			return line.value, true
+		}
+	}
+	return "", false
+}
+
+// set changes a key in place, a key missing from the file is appended.
+func (p *properties) set(key, value string) {
+	line := propertyLine{key: key, value: value, raw: key + "=" + value}
+	for i := range p.lines {
+		if p.lines[i].key == key {
+			p.lines[i] = line
+			return
+		}
+	}
+	p.lines = append(p.lines, line)
+	p.trailing = true
+}
+
+// unset removes a key, bedrock_server then uses its default.
+func (p *properties) unset(key string) {
+	lines := p.lines[:0]
+	for _, line := range p.lines {
+		if line.key != key {
+			lines = append(lines, line)
+		}
+	}
+	p.lines = lines
+}
+
+func (p *properties) values() map[string]string {
+	values := make(map[string]string)
+	for _, line := range p.lines {
+		if line.key != "" {
+			values[line.key] = line.value
+		}
+	}
+	return values
+}
+
+func (p *properties) typed() map[string]interface{} {
+	values := make(map[string]interface{})
+	for _, line := range p.lines {
+		if line.key == "" {
+			continue
+		}
+		values[line.key] = typedProperty(line.key, line.value)
+	}
+	return values
+}
+
+// typedProperty converts a value by the schema, values the schema type does
+// not fit stay strings.
+func typedProperty(key, value string) interface{} {
+	spec, ok := propertySpecs[key]
+	if !ok {
+		return value
+	}
+	switch spec.Type {
+	case propBool:
+		if b, err := strconv.ParseBool(value); err == nil {
+			return b
+		}
+	case propInt:
+		if n, err := strconv.Atoi(value); err == nil {
+			return n
+		}
+	case propFloat:
+		if f, err := strconv.ParseFloat(value, 64); err == nil {
+			return f
+		}
+	}
+	return value
+}
+
+// propertyValue validates a json value against the schema and returns it as
+// written in server.properties.
+func propertyValue(spec dto.PropertySpec, raw json.RawMessage) (string, error) {
+	var v interface{}
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return "", fmt.Errorf("%w: %s: %s", utils.ErrInvalidProp, spec.Key, err)
+	}
+
+	var value string
+	switch t := v.(type) {
+	case string:
+		value = strings.TrimSpace(t)
+	case bool:
+		value = strconv.FormatBool(t)
+	case json.Number:
+		value = t.String()
+	default:
+		return "", fmt.Errorf("%w: %s must be a %s", utils.ErrInvalidProp, spec.Key, spec.Type)
+	}
+	if strings.ContainsAny(value, "\r\n") {
+		return "", fmt.Errorf("%w: %s must be a single line", utils.ErrInvalidProp, spec.Key)
+	}
+
+	for _, word := range spec.Enum {
+		if value == word {
+			return value, nil
+		}
+	}
+
+	switch spec.Type {
+	case propBool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return "", fmt.Errorf("%w: %s must be true or false", utils.ErrInvalidProp, spec.Key)
+		}
+		return strconv.FormatBool(b), nil
+	case propInt, propFloat:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+			return "", fmt.Errorf("%w: %s must be a number", utils.ErrInvalidProp, spec.Key)
+		}
+		if spec.Type == propInt && f != math.Trunc(f) {
+			return "", fmt.Errorf("%w: %s must be a whole number", utils.ErrInvalidProp, spec.Key)
+		}
+		if (spec.Min != nil && f < *spec.Min) || (spec.Max != nil && f > *spec.Max) {
+			return "", fmt.Errorf("%w: %s must be %s", utils.ErrInvalidProp, spec.Key, rangeText(spec))
+		}
+		if spec.Type == propInt {
+			return strconv.FormatInt(int64(f), 10), nil
+		}
+		return strconv.FormatFloat(f, 'f', -1, 64), nil
+	case propEnum:
+		return "", fmt.Errorf("%w: %s must be one of %s", utils.ErrInvalidProp, spec.Key, strings.Join(spec.Enum, ", "))
+	}
+	return value, nil
+}
+
+func rangeText(spec dto.PropertySpec) string {
+	switch {
+	case spec.Min != nil && spec.Max != nil:
+		return fmt.Sprintf("between %g and %g", *spec.Min, *spec.Max)
+	case spec.Min != nil:
+		return fmt.Sprintf("at least %g", *spec.Min)
+	default:
+		return fmt.Sprintf("at most %g", *spec.Max)
+	}
+}
+
+func propertiesPath(world string) string {
+	return filepath.Join("data/servers", world, "server.properties")
+}
+
+func readProperties(world string) (*properties, error) {
+	data, err := os.ReadFile(propertiesPath(world))
+	if err != nil {
+		return nil, err
+	}
+	return parseProperties(data), nil
+}
+
+// editProperties reads, changes and writes back the server.properties of a
+// world, edits of all worlds run one at a time.
+func (u *bedrockUC) editProperties(world string, edit func(p *properties) error) error {
+	u.propsMu.Lock()
+	defer u.propsMu.Unlock()
+
+	p, err := readProperties(world)
+	if err != nil {
+		return err
+	}
+	if err := edit(p); err != nil {
+		return err
+	}
+	return writeFileAtomic(propertiesPath(world), p.bytes(), 0644)
+}
+
+// GetPropertySchema lists every key the properties API accepts.
+func (u *bedrockUC) GetPropertySchema() []dto.PropertySpec {
+	return propertySchema
+}
+
+func (u *bedrockUC) GetProperties(name string) (*dto.ServerProperties, error) {
+	if _, err := u.bedRepo.GetWorldByName(name); err != nil {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+	p, err := readProperties(name)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.ServerProperties{World: name, Properties: p.typed()}, nil
+}
+
+// PatchProperties changes the given keys, null removes a key from the file.
+// The whole patch is validated before anything is written. A running world
+// takes what it can through the console, the rest waits for a restart.
+func (u *bedrockUC) PatchProperties(name string, patch map[string]json.RawMessage, restart bool) (*dto.ServerProperties, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+	if len(patch) == 0 {
+		return nil, fmt.Errorf("%w: nothing to change", utils.ErrInvalidProp)
+	}
+
+	keys := make([]string, 0, len(patch))
+	for key := range patch {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make(map[string]*string, len(patch))
+	for _, key := range keys {
+		spec, ok := propertySpecs[key]
+		if !ok {
+			return nil, fmt.Errorf("%w: unknown key %s", utils.ErrInvalidProp, key)
+		}
+		if spec.Managed {
+			return nil, fmt.Errorf("%w: %s follows the world settings, change it with the world update", utils.ErrInvalidProp, key)
+		}
+		if string(bytes.TrimSpace(patch[key])) == "null" {
+			values[key] = nil
+			continue
+		}
+		value, err := propertyValue(spec, patch[key])
+		if err != nil {
+			return nil, err
+		}
+		values[key] = &value
+	}
+
+	var result *properties
+	err = u.editProperties(name, func(p *properties) error {
+		for _, key := range keys {
+			if values[key] == nil {
+				p.unset(key)
+			} else {
+				p.set(key, *values[key])
+			}
+		}
+		result = p
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]interface{})
+	for _, key := range keys {
+		column, ok := worldColumns[key]
+		if !ok {
+			continue
+		}
+		// an unset key is back at the default of bedrock_server
+		value := propertySpecs[key].Default
+		if values[key] != nil {
+			value = *values[key]
+		}
+		columns[column] = typedProperty(key, value)
+	}
+	if err := u.bedRepo.UpdateWorldProperties(worlddb.ID, columns); err != nil {
+		return nil, err
+	}
+
+	applied, err := u.applySettings(name, nil, restart)
+	if err != nil {
+		return nil, err
+	}
+	return &dto.ServerProperties{World: name, Properties: result.typed(), ApplyResult: *applied}, nil
+}
@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"encoding/json"
+	"errors"
+	"minecrat_go/helper/utils"
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestPropertiesRoundTrip(t *testing.T) {
+	files := map[string]string{
+		"lf":                "server-name=alpha\nmax-players=10\n",
+		"crlf":              "server-name=alpha\r\nmax-players=10\r\n",
+		"no trailing eol":   "server-name=alpha\nmax-players=10",
+		"comments":          "# Used as the server name\n# Allowed values: Any string\nserver-name=alpha\n\n  # indented\nmax-players = 10 \n",
+		"not a property":    "server-name=alpha\njust some words\n",
+		"blank lines kept":  "\n\nserver-name=alpha\n\n",
+		"value with equals": "level-seed=a=b\n",
+	}
+
+	for name, data := range files {
+		t.Run(name, func(t *testing.T) {
+			if got := string(parseProperties([]byte(data)).bytes()); got != data {
+				t.Errorf("round trip = %q, want %q", got, data)
+			}
+		})
+	}
+
+	p := parseProperties([]byte("max-players = 10 \nlevel-seed=a=b\n"))
+	if v, _ := p.get("max-players"); v != "10" {
+		t.Errorf("max-players = %q, want 10", v)
+	}
+	if v, _ := p.get("level-seed"); v != "a=b" {
+		t.Errorf("level-seed = %q, want a=b", v)
+	}
+}
+
+func TestPatchPropertiesKeepsLayout(t *testing.T) {
+	tests := []struct {
+		name string
+		file string
+		want string
+	}{
+		{
+			name: "lf",
+			file: "# Used as the server name\nserver-name=alpha\n\n# Allowed values: \"easy\"\ndifficulty=easy\nlevel-seed=123\nmax-players=10\n",
+			want: "# Used as the server name\nserver-name=alpha\n\n# Allowed values: \"easy\"\ndifficulty=hard\nmax-players=20\nallow-list=true\n",
+		},
+		{
+			name: "crlf",
+			file: "# Used as the server name\r\nserver-name=alpha\r\ndifficulty=easy\r\nlevel-seed=123\r\nmax-players=10\r\n",
+			want: "# Used as the server name\r\nserver-name=alpha\r\ndifficulty=hard\r\nmax-players=20\r\nallow-list=true\r\n",
+		},
+		{
+			name: "no trailing eol",
+			file: "server-name=alpha\ndifficulty=easy\nlevel-seed=123\nmax-players=10",
+			want: "server-name=alpha\ndifficulty=hard\nmax-players=20\nallow-list=true\n",
+		},
+	}
+
+	patch := map[string]json.RawMessage{
+		"difficulty":  json.RawMessage(`"hard"`),
+		"max-players": json.RawMessage(`20`),
+		"level-seed":  json.RawMessage(`null`),
+		"allow-list":  json.RawMessage(`true`),
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := newTestUC(t, NewFakeRuntime(), testWorld("alpha", 1))
+			if err := os.WriteFile(propertiesPath("alpha"), []byte(tt.file), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := u.PatchProperties("alpha", patch, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			data, err := os.ReadFile(propertiesPath("alpha"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("file = %q\nwant   %q", data, tt.want)
+			}
+			if result.Properties["max-players"] != 20 || result.Properties["allow-list"] != true {
+				t.Errorf("properties = %v, want typed values", result.Properties)
+			}
+		})
+	}
+}
+
+func TestPropertyValue(t *testing.T) {
+	tests := []struct {
+		key   string
+		value string
+		want  string
+		ok    bool
+	}{
+		{"max-players", `20`, "20", true},
+		{"max-players", `"20"`, "20", true},
+		{"max-players", `20.0`, "20", true},
+		{"max-players", `20.5`, "", false},
+		{"max-players", `0`, "", false},
+		{"max-players", `"many"`, "", false},
+		{"max-players", `true`, "", false},
+		{"max-players", `[20]`, "", false},
+		{"tick-distance", `12`, "12", true},
+		{"tick-distance", `13`, "", false},
+		{"tick-distance", `3`, "", false},
+		{"player-movement-action-direction-threshold", `0.5`, "0.5", true},
+		{"player-movement-action-direction-threshold", `1.5`, "", false},
+		{"player-movement-action-direction-threshold", `-0.1`, "", false},
+		{"player-movement-distance-threshold", `"NaN"`, "", false},
+		{"server-build-radius-ratio", `"Disabled"`, "Disabled", true},
+		{"server-build-radius-ratio", `0.25`, "0.25", true},
+		{"server-build-radius-ratio", `2`, "", false},
+		{"difficulty", `"hard"`, "hard", true},
+		{"difficulty", `"Hard"`, "", false},
+		{"difficulty", `"insane"`, "", false},
+		{"difficulty", `3`, "", false},
+		{"allow-cheats", `true`, "true", true},
+		{"allow-cheats", `"1"`, "true", true},
+		{"allow-cheats", `"yes"`, "", false},
+		{"server-name", `" alpha "`, "alpha", true},
+		{"server-name", `"alpha\nallow-cheats=true"`, "", false},
+		{"level-seed", `"1\r2"`, "", false},
+	}
+
+	for _, tt := range tests {
+		got, err := propertyValue(propertySpecs[tt.key], json.RawMessage(tt.value))
+		if !tt.ok {
+			if !errors.Is(err, utils.ErrInvalidProp) {
+				t.Errorf("%s=%s: err = %v, want ErrInvalidProp", tt.key, tt.value, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("%s=%s = %q, %v, want %q", tt.key, tt.value, got, err, tt.want)
+		}
+	}
+}
+
+func TestPatchPropertiesMirrorsColumns(t *testing.T) {
+	u, repo := newTestUC(t, NewFakeRuntime(), testWorld("alpha", 1))
+	file := "server-name=alpha\ngamemode=creative\ndifficulty=hard\nlevel-seed=123\nmax-players=10\n"
+	if err := os.WriteFile(propertiesPath("alpha"), []byte(file), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	patch := map[string]json.RawMessage{
+		"difficulty":  json.RawMessage(`"peaceful"`),
+		"max-players": json.RawMessage(`20`),
+		"gamemode":    json.RawMessage(`null`),
+		"level-seed":  json.RawMessage(`null`),
+		"allow-list":  json.RawMessage(`true`),
+	}
+	if _, err := u.PatchProperties("alpha", patch, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// unset keys are back at their default in the row too
+	want := map[string]interface{}{
+		"difficult":  "peaceful",
+		"max_player": 20,
+		"game_mode":  "survival",
+		"seed_world": "",
+	}
+	if got := repo.columns[1]; !reflect.DeepEqual(got, want) {
+		t.Errorf("columns = %v, want %v", got, want)
+	}
+}
+
+func TestPatchPropertiesRejects(t *testing.T) {
+	const file = "server-name=alpha\nmax-players=10\n"
+
+	tests := []struct {
+		name  string
+		patch map[string]json.RawMessage
+	}{
+		{"nothing", map[string]json.RawMessage{}},
+		{"unknown key", map[string]json.RawMessage{"motd": json.RawMessage(`"hi"`)}},
+		{"managed key", map[string]json.RawMessage{"server-port": json.RawMessage(`19140`)}},
+		// one bad value fails the whole patch
+		{"bad value", map[string]json.RawMessage{
+			"max-players": json.RawMessage(`20`),
+			"difficulty":  json.RawMessage(`"insane"`),
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u, _ := newTestUC(t, NewFakeRuntime(), testWorld("alpha", 1))
+			if err := os.WriteFile(propertiesPath("alpha"), []byte(file), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := u.PatchProperties("alpha", tt.patch, false); !errors.Is(err, utils.ErrInvalidProp) {
+				t.Fatalf("err = %v, want ErrInvalidProp", err)
+			}
+			if data, _ := os.ReadFile(propertiesPath("alpha")); string(data) != file {
+				t.Errorf("file changed to %q", data)
+			}
+		})
+	}
+}
@@ -0,0 +1,226 @@
+package usecase
+
+import (
+	"bufio"
+	"encoding/json"
+	"log"
+	"minecrat_go/dto"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	OrphanAdopt     = "adopt"
+	OrphanTerminate = "terminate"
+
+	procStateFile  = "manager.json"
+	consoleLogFile = "console.log"
+)
+
+// procState is persisted next to every running world so a restarted manager
+// can find the bedrock_server processes it left behind.
+type procState struct {
+	Pid       int       `json:"pid"`
+	WorldId   uint      `json:"world_id"`
+	Port      int       `json:"port"`
+	State     string    `json:"state"`
+	StartedAt time.Time `json:"started_at"`
+}
+
+func procStatePath(name string) string {
+	return filepath.Join("data/servers", name, procStateFile)
+}
+
+func (u *bedrockUC) saveProcState(name string, server *BedrockServer, state ServerState) {
+	output, err := json.MarshalIndent(procState{
+		Pid:       server.Proc.Pid(),
+		WorldId:   server.Id,
+		Port:      server.Port,
+		State:     string(state),
+		StartedAt: server.StartedAt,
+	}, "", "  ")
+	if err != nil {
+		return
+	}
+
+	path := procStatePath(name)
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, output, 0644); err != nil {
+		log.Printf("server %s: save state failed: %s", name, err)
+		return
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		log.Printf("server %s: save state failed: %s", name, err)
+	}
+}
+
+func (u *bedrockUC) removeProcState(name string) {
+	if err := os.Remove(procStatePath(name)); err != nil && !os.IsNotExist(err) {
+		log.Printf("server %s: remove state failed: %s", name, err)
+	}
+}
+
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	return proc.Signal(syscall.Signal(0)) == nil
+}
+
+// isBedrockProcess makes sure a persisted pid still belongs to the
+// bedrock_server of that world and was not reused by something else.
+func isBedrockProcess(pid int, dir string) bool {
+	if !processAlive(pid) {
+		return false
+	}
+
+	cmdline, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "cmdline"))
+	if err != nil || !strings.Contains(string(cmdline), "bedrock_server") {
+		return false
+	}
+
+	cwd, err := os.Readlink(filepath.Join("/proc", strconv.Itoa(pid), "cwd"))
+	if err != nil {
+		return false
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	return cwd == abs
+}
+
+// Reconcile runs once on boot: it adopts or terminates bedrock_server
+// processes left behind by a previous manager, settles the player sessions
+// they had open and imports the permission and allowlist files of worlds
+// older than the permission tables.
+func (u *bedrockUC) Reconcile() error {
+	entries, err := os.ReadDir("data/servers")
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		name := entry.Name()
+
+		raw, err := os.ReadFile(procStatePath(name))
+		if err != nil {
+			continue
+		}
+		var state procState
+		if err := json.Unmarshal(raw, &state); err != nil {
+			log.Printf("server %s: invalid %s: %s", name, procStateFile, err)
+			u.removeProcState(name)
+			continue
+		}
+
+		if !isBedrockProcess(state.Pid, filepath.Join("data/servers", name)) {
+			u.removeProcState(name)
+			continue
+		}
+
+		if u.orphanPolicy == OrphanTerminate {
+			u.terminateOrphan(name, state.Pid)
+			continue
+		}
+		u.adoptOrphan(name, state)
+	}
+
+	u.restoreSessions()
+	u.importAccessFiles()
+	return nil
+}
+
+func (u *bedrockUC) adoptOrphan(name string, state procState) {
+	req := dto.StartServerReq{Name: name, WorldId: state.WorldId, Port: state.Port}
+	sup, err := u.resetSupervisor(&req)
+	if err != nil {
+		log.Printf("server %s: adopt failed: %s", name, err)
+		return
+	}
+
+	dir := filepath.Join("data/servers", name)
+	server := &BedrockServer{
+		Proc:      adoptProcess(state.Pid, dir),
+		Adopted:   true,
+		Port:      req.Port,
+		Name:      dir,
+		Id:        req.WorldId,
+		Logs:      make([]string, 0, 1001),
+		StartedAt: state.StartedAt,
+		done:      make(chan struct{}),
+		scanDone:  make(chan struct{}),
+		ready:     make(chan struct{}),
+		Online:    make(map[string]onlinePlayer),
+	}
+
+	if _, err := os.Stat(u.cgroupPath(name)); err == nil {
+		server.Cgroup = u.cgroupPath(name)
+	}
+	// nothing tells what the process read, take the file as applied
+	u.snapshotSettings(name, server)
+
+	// keep the history for GetServerLogs, the process only tails new lines
+	if console, err := os.Open(filepath.Join(dir, consoleLogFile)); err == nil {
+		server.Logs = lastLines(console, 1000)
+		console.Close()
+	}
+
+	// it survived the previous manager, so it is past its startup
+	server.markReady(nil)
+	sup.setState(StateRunning)
+
+	u.s.Lock()
+	u.servers[name] = server
+	u.s.Unlock()
+
+	go u.supervise(name, server, sup)
+	go u.scanOutput(name, server, server.Proc.Output())
+
+	log.Printf("server %s: adopted orphaned bedrock_server pid %d", name, state.Pid)
+}
+
+func lastLines(f *os.File, n int) []string {
+	lines := make([]string, 0, n+1)
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		if len(lines) >= n {
+			lines = lines[1:]
+		}
+		lines = append(lines, scanner.Text())
+	}
+	return lines
+}
+
+func (u *bedrockUC) terminateOrphan(name string, pid int) {
+	proc, err := os.FindProcess(pid)
+	if err != nil {
+		return
+	}
+
+	log.Printf("server %s: terminating orphaned bedrock_server pid %d", name, pid)
+	if err := proc.Signal(syscall.SIGTERM); err != nil {
+		log.Printf("server %s: sigterm failed: %s", name, err)
+	}
+
+	deadline := time.Now().Add(u.stopTimeout)
+	for processAlive(pid) && time.Now().Before(deadline) {
+		time.Sleep(500 * time.Millisecond)
+	}
+	if processAlive(pid) {
+		proc.Kill()
+	}
+	u.removeProcState(name)
+}
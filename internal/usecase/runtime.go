@@ -0,0 +1,208 @@
+package usecase
+
+import (
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+)
+
+// RuntimeSpec describes the world a runtime has to launch.
+type RuntimeSpec struct {
+	Name    string
+	Dir     string
+	WorldId uint
+	Port    int
+	PortV6  int
+	// Runtime selects the isolation of the world, see RuntimeRouter.
+	Runtime string
+	// Cgroup is the cgroup v2 directory the process has to start in.
+	Cgroup string
+	Limits cgroupLimits
+}
+
+// ServerRuntime launches bedrock_server instances. The default runtime execs
+// the real binary, tests and local development can use FakeRuntime.
+type ServerRuntime interface {
+	Start(spec RuntimeSpec) (ServerProcess, error)
+}
+
+// ServerProcess is one running bedrock_server.
+type ServerProcess interface {
+	Pid() int
+	// Stdin is the console input, it is nil when the process has no console.
+	Stdin() io.Writer
+	// Output is stdout and stderr combined, it returns io.EOF after exit.
+	Output() io.Reader
+	// Wait blocks until the process exits and returns its exit code,
+	// -1 when it was killed by a signal or the code is unknown.
+	Wait() (int, error)
+	Signal(sig os.Signal) error
+}
+
+type execRuntime struct {
+	binary string
+}
+
+func NewExecRuntime() ServerRuntime {
+	return &execRuntime{binary: "./bedrock_server"}
+}
+
+func (r *execRuntime) Start(spec RuntimeSpec) (ServerProcess, error) {
+	cmd := exec.Command(r.binary)
+	cmd.Dir = spec.Dir
+	return startCmd(cmd, spec.Dir, spec.Cgroup)
+}
+
+// startCmd starts cmd with its stdout and stderr going to the console file
+// instead of a pipe, so the server keeps running if the manager dies and the
+// manager tails it back in.
+func startCmd(cmd *exec.Cmd, dir, cgroup string) (*execProcess, error) {
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+
+	consolePath := filepath.Join(dir, consoleLogFile)
+	consoleW, err := os.OpenFile(consolePath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC|os.O_APPEND, 0644)
+	if err != nil {
+		stdin.Close()
+		return nil, err
+	}
+	cmd.Stdout = consoleW
+	cmd.Stderr = consoleW
+	detachProcess(cmd)
+	if cgroup != "" {
+		cgroupDir, err := placeInCgroup(cmd, cgroup)
+		if err != nil {
+			stdin.Close()
+			consoleW.Close()
+			return nil, err
+		}
+		defer cgroupDir.Close()
+	}
+
+	if err := cmd.Start(); err != nil {
+		stdin.Close()
+		consoleW.Close()
+		return nil, err
+	}
+	consoleW.Close()
+
+	console, err := os.Open(consolePath)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+
+	exited := make(chan struct{})
+	return &execProcess{
+		cmd:    cmd,
+		stdin:  stdin,
+		output: &tailReader{f: console, exited: exited},
+		exited: exited,
+	}, nil
+}
+
+type execProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	output *tailReader
+	exited chan struct{}
+}
+
+func (p *execProcess) Pid() int          { return p.cmd.Process.Pid }
+func (p *execProcess) Stdin() io.Writer  { return p.stdin }
+func (p *execProcess) Output() io.Reader { return p.output }
+
+func (p *execProcess) Signal(sig os.Signal) error {
+	return p.cmd.Process.Signal(sig)
+}
+
+func (p *execProcess) Wait() (int, error) {
+	err := p.cmd.Wait()
+	close(p.exited)
+
+	var exitErr *exec.ExitError
+	if err != nil && !errors.As(err, &exitErr) {
+		return -1, err
+	}
+	return p.cmd.ProcessState.ExitCode(), err
+}
+
+// adoptedProcess is a bedrock_server left behind by a previous manager. It
+// is not our child, so it has no console and can only be polled and signalled.
+type adoptedProcess struct {
+	pid    int
+	output *tailReader
+	exited chan struct{}
+}
+
+// adoptProcess tails the console file of dir from its current end.
+func adoptProcess(pid int, dir string) *adoptedProcess {
+	p := &adoptedProcess{pid: pid, exited: make(chan struct{})}
+	if console, err := os.Open(filepath.Join(dir, consoleLogFile)); err == nil {
+		console.Seek(0, io.SeekEnd)
+		p.output = &tailReader{f: console, exited: p.exited}
+	}
+	return p
+}
+
+func (p *adoptedProcess) Pid() int         { return p.pid }
+func (p *adoptedProcess) Stdin() io.Writer { return nil }
+
+func (p *adoptedProcess) Output() io.Reader {
+	if p.output == nil {
+		return nil
+	}
+	return p.output
+}
+
+func (p *adoptedProcess) Signal(sig os.Signal) error {
+	proc, err := os.FindProcess(p.pid)
+	if err != nil {
+		return err
+	}
+	return proc.Signal(sig)
+}
+
+func (p *adoptedProcess) Wait() (int, error) {
+	for processAlive(p.pid) {
+		time.Sleep(2 * time.Second)
+	}
+	close(p.exited)
+	return -1, nil
+}
+
+// tailReader reads a file that is still being written, like tail -f. It
+// returns io.EOF only once exited is closed and everything has been read.
+type tailReader struct {
+	f      *os.File
+	exited <-chan struct{}
+}
+
+func (t *tailReader) Read(p []byte) (int, error) {
+	for {
+		n, err := t.f.Read(p)
+		if n > 0 || (err != nil && err != io.EOF) {
+			return n, err
+		}
+
+		select {
+		case <-t.exited:
+			n, err := t.f.Read(p)
+			if n > 0 {
+				return n, nil
+			}
+			if err == nil || err == io.EOF {
+				t.f.Close()
+				err = io.EOF
+			}
+			return 0, err
+		case <-time.After(200 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package usecase
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// FakeRuntime behaves like bedrock_server without needing the Mojang binary:
+// it prints the startup banner, answers list, save hold/query/resume and
+// stop, and lets the caller script player joins, crashes and hangs.
+type FakeRuntime struct {
+	Version      string
+	StartupDelay time.Duration
+	// FailStart is returned from Start when set.
+	FailStart error
+	// HangOnStop ignores the stop command, IgnoreSigterm ignores SIGTERM.
+	HangOnStop    bool
+	IgnoreSigterm bool
+	// PortOccupied fails the startup like a second server on the same port.
+	PortOccupied bool
+	// OnStart runs in its own goroutine once the banner has been printed.
+	OnStart func(p *FakeProcess)
+
+	mu    sync.Mutex
+	procs map[string]*FakeProcess
+	pid   int
+}
+
+func NewFakeRuntime() *FakeRuntime {
+	return &FakeRuntime{
+		Version: "1.21.90.4",
+		procs:   make(map[string]*FakeProcess),
+		pid:     100000,
+	}
+}
+
+// Process returns the last process started for a world.
+func (r *FakeRuntime) Process(name string) *FakeProcess {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.procs[name]
+}
+
+func (r *FakeRuntime) Start(spec RuntimeSpec) (ServerProcess, error) {
+	if r.FailStart != nil {
+		return nil, r.FailStart
+	}
+
+	r.mu.Lock()
+	r.pid++
+	stdinR, stdinW := io.Pipe()
+	outR, outW := io.Pipe()
+	p := &FakeProcess{
+		Name:          spec.Name,
+		pid:           r.pid,
+		port:          spec.Port,
+		portV6:        spec.PortV6,
+		hangOnStop:    r.HangOnStop,
+		ignoreSigterm: r.IgnoreSigterm,
+		portOccupied:  r.PortOccupied,
+		stdin:         stdinW,
+		stdinR:        stdinR,
+		out:           outW,
+		outR:          outR,
+		players:       make(map[string]string),
+		exit:          make(chan struct{}),
+	}
+	r.procs[spec.Name] = p
+	r.mu.Unlock()
+
+	go p.run(r.Version, r.StartupDelay, r.OnStart)
+	return p, nil
+}
+
+type FakeProcess struct {
+	Name string
+
+	pid           int
+	port          int
+	portV6        int
+	hangOnStop    bool
+	ignoreSigterm bool
+	portOccupied  bool
+
+	stdin  *io.PipeWriter
+	stdinR *io.PipeReader
+	out    *io.PipeWriter
+	outR   *io.PipeReader
+
+	mu       sync.Mutex
+	players  map[string]string // xuid -> name
+	commands []string
+	saveHeld bool
+	exitCode int
+	exited   bool
+	exit     chan struct{}
+}
+
+func (p *FakeProcess) Pid() int          { return p.pid }
+func (p *FakeProcess) Stdin() io.Writer  { return p.stdin }
+func (p *FakeProcess) Output() io.Reader { return p.outR }
+
+func (p *FakeProcess) Wait() (int, error) {
+	<-p.exit
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.exitCode != 0 {
+		return p.exitCode, fmt.Errorf("exit status %d", p.exitCode)
+	}
+	return 0, nil
+}
+
+func (p *FakeProcess) Signal(sig os.Signal) error {
+	switch sig {
+	case os.Kill:
+		p.Exit(-1)
+	case syscall.SIGTERM, os.Interrupt:
+		if p.ignoreSigterm {
+			return nil
+		}
+		go p.shutdown()
+	}
+	return nil
+}
+
+// Commands returns the lines received on stdin so far.
+func (p *FakeProcess) Commands() []string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]string(nil), p.commands...)
+}
+
+// Emit writes a raw line to the output stream with the bedrock log prefix.
+func (p *FakeProcess) Emit(level, msg string) {
+	p.mu.Lock()
+	exited := p.exited
+	p.mu.Unlock()
+	if exited {
+		return
+	}
+	now := time.Now()
+	fmt.Fprintf(p.out, "[%s:%03d %s] %s\n", now.Format("2006-01-02 15:04:05"), now.Nanosecond()/1e6, level, msg)
+}
+
+func (p *FakeProcess) Connect(name, xuid string) {
+	p.mu.Lock()
+	p.players[xuid] = name
+	p.mu.Unlock()
+	p.Emit("INFO", fmt.Sprintf("Player connected: %s, xuid: %s", name, xuid))
+}
+
+func (p *FakeProcess) Disconnect(name, xuid string) {
+	p.mu.Lock()
+	delete(p.players, xuid)
+	p.mu.Unlock()
+	p.Emit("INFO", fmt.Sprintf("Player disconnected: %s, xuid: %s", name, xuid))
+}
+
+// Crash ends the process with code without a clean shutdown.
+func (p *FakeProcess) Crash(code int) {
+	p.Emit("ERROR", "Crash detected, the server will exit")
+	p.Exit(code)
+}
+
+func (p *FakeProcess) Exit(code int) {
+	p.mu.Lock()
+	if p.exited {
+		p.mu.Unlock()
+		return
+	}
+	p.exited = true
+	p.exitCode = code
+	p.mu.Unlock()
+
+	p.out.Close()
+	p.stdinR.Close()
+	close(p.exit)
+}
+
+func (p *FakeProcess) run(version string, delay time.Duration, onStart func(p *FakeProcess)) {
+	p.Emit("INFO", "Starting Server")
+	p.Emit("INFO", "Version: "+version)
+	p.Emit("INFO", "Session ID: 00000000-0000-0000-0000-000000000000")
+	p.Emit("INFO", "Level Name: "+p.Name)
+	p.Emit("INFO", "Game mode: 0 Survival")
+	p.Emit("INFO", "Difficulty: 1 EASY")
+	time.Sleep(delay)
+	if p.portOccupied {
+		p.Emit("ERROR", "Network port occupied, can't start server.")
+		p.Exit(1)
+		return
+	}
+	p.Emit("INFO", fmt.Sprintf("IPv4 supported, port: %d: Used for gameplay and LAN discovery", p.port))
+	portV6 := p.portV6
+	if portV6 == 0 {
+		portV6 = p.port + 1
+	}
+	p.Emit("INFO", fmt.Sprintf("IPv6 supported, port: %d: Used for gameplay", portV6))
+	p.Emit("INFO", "Server started.")
+
+	if onStart != nil {
+		go onStart(p)
+	}
+
+	scanner := bufio.NewScanner(p.stdinR)
+	for scanner.Scan() {
+		cmd := strings.TrimSpace(scanner.Text())
+		p.mu.Lock()
+		p.commands = append(p.commands, cmd)
+		p.mu.Unlock()
+		p.command(cmd)
+	}
+}
+
+func (p *FakeProcess) command(cmd string) {
+	switch {
+	case cmd == "":
+	case cmd == "stop":
+		if p.hangOnStop {
+			return
+		}
+		go p.shutdown()
+	case cmd == "list":
+		p.mu.Lock()
+		names := make([]string, 0, len(p.players))
+		for _, name := range p.players {
+			names = append(names, name)
+		}
+		p.mu.Unlock()
+		p.Emit("INFO", fmt.Sprintf("There are %d/10 players online:", len(names)))
+		p.Emit("INFO", strings.Join(names, ", "))
+	case cmd == "save hold":
+		p.mu.Lock()
+		p.saveHeld = true
+		p.mu.Unlock()
+		p.Emit("INFO", "Saving...")
+	case cmd == "save query":
+		p.mu.Lock()
+		held := p.saveHeld
+		p.mu.Unlock()
+		if !held {
+			p.Emit("INFO", "A previous save has not been completed.")
+			return
+		}
+		p.Emit("INFO", "Data saved. Files are now ready to be copied.")
+		p.Emit("INFO", fmt.Sprintf("%s/db/CURRENT:16, %s/level.dat:2552", p.Name, p.Name))
+	case cmd == "save resume":
+		p.mu.Lock()
+		p.saveHeld = false
+		p.mu.Unlock()
+		p.Emit("INFO", "Changes to the level are resumed.")
+	case strings.HasPrefix(cmd, "kick "):
+		p.kick(strings.TrimPrefix(cmd, "kick "))
+	case strings.HasPrefix(cmd, "tell "):
+		// tell answers on the console only when nobody matched
+		if _, ok := p.target(strings.TrimPrefix(cmd, "tell ")); !ok {
+			p.Emit("ERROR", "No targets matched selector")
+		}
+	case strings.HasPrefix(cmd, "ability "):
+		p.ability(strings.TrimPrefix(cmd, "ability "))
+	case cmd == "allowlist reload":
+		p.Emit("INFO", "Allowlist file reloaded.")
+	case cmd == "allowlist on":
+		p.Emit("INFO", "Allowlist enabled.")
+	case cmd == "allowlist off":
+		p.Emit("INFO", "Allowlist disabled.")
+	case cmd == "permission reload":
+		p.Emit("INFO", "Permissions file reloaded.")
+	case strings.HasPrefix(cmd, "difficulty "):
+		p.Emit("INFO", "Set game difficulty to "+strings.TrimPrefix(cmd, "difficulty "))
+	case strings.HasPrefix(cmd, "defaultgamemode "):
+		p.Emit("INFO", "The default game mode is now "+strings.TrimPrefix(cmd, "defaultgamemode "))
+	case strings.HasPrefix(cmd, "gamerule "):
+		if rule, value, ok := strings.Cut(strings.TrimPrefix(cmd, "gamerule "), " "); ok {
+			p.Emit("INFO", fmt.Sprintf("Game rule %s has been updated to %s", rule, value))
+		}
+	case strings.HasPrefix(cmd, "say "):
+		p.Emit("INFO", "[Server] "+strings.TrimPrefix(cmd, "say "))
+	default:
+		name := strings.Fields(cmd)[0]
+		p.Emit("ERROR", fmt.Sprintf("Unknown command: %s. Please check that the command exists and that you have permission to use it.", name))
+	}
+}
+
+// target splits `"name" rest` or `name rest` like bedrock_server does and
+// reports whether the name is online.
+func (p *FakeProcess) target(args string) (fakeTarget, bool) {
+	var t fakeTarget
+	if rest, ok := strings.CutPrefix(args, `"`); ok {
+		t.name, t.rest, _ = strings.Cut(rest, `"`)
+	} else {
+		t.name, t.rest, _ = strings.Cut(args, " ")
+	}
+	t.rest = strings.TrimSpace(t.rest)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for x, n := range p.players {
+		if strings.EqualFold(n, t.name) {
+			t.xuid = x
+			return t, true
+		}
+	}
+	return t, false
+}
+
+type fakeTarget struct {
+	name, xuid, rest string
+}
+
+func (p *FakeProcess) kick(args string) {
+	t, ok := p.target(args)
+	if !ok {
+		p.Emit("ERROR", "No targets matched selector")
+		return
+	}
+
+	if t.rest != "" {
+		p.Emit("INFO", fmt.Sprintf("Kicked %s from the game: '%s'", t.name, t.rest))
+	} else {
+		p.Emit("INFO", fmt.Sprintf("Kicked %s from the game", t.name))
+	}
+	p.Disconnect(t.name, t.xuid)
+}
+
+// ability behaves as on a world with cheats and education features on.
+func (p *FakeProcess) ability(args string) {
+	t, ok := p.target(args)
+	if !ok {
+		p.Emit("ERROR", "No targets matched selector")
+		return
+	}
+	p.Emit("INFO", fmt.Sprintf("The ability %s for %s has been updated", t.rest, t.name))
+}
+
+func (p *FakeProcess) shutdown() {
+	p.Emit("INFO", "Server stop requested.")
+	p.Emit("INFO", "Stopping server...")
+	p.Emit("INFO", "Quit correctly")
+	p.Exit(0)
+}
@@ -0,0 +1,325 @@
+package usecase
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	RuntimeProcess   = "process"
+	RuntimeNamespace = "namespace"
+	RuntimeOCI       = "oci"
+)
+
+const inspectTimeout = 3 * time.Second
+
+// RuntimeRouter picks the runtime configured on the world.
+type RuntimeRouter map[string]ServerRuntime
+
+func (r RuntimeRouter) Start(spec RuntimeSpec) (ServerProcess, error) {
+	name := spec.Runtime
+	if name == "" {
+		name = RuntimeProcess
+	}
+	runtime, ok := r[name]
+	if !ok {
+		return nil, fmt.Errorf("runtime %q is not available", name)
+	}
+	return runtime.Start(spec)
+}
+
+// publishedPorts are the UDP ports a sandboxed world needs on the host.
+func publishedPorts(spec RuntimeSpec) []int {
+	ports := []int{spec.Port}
+	if spec.PortV6 != 0 && spec.PortV6 != spec.Port {
+		ports = append(ports, spec.PortV6)
+	}
+	return ports
+}
+
+// namespaceRuntime runs bedrock_server under bubblewrap in fresh user, pid,
+// ipc, uts, mount and network namespaces. Only the world directory is
+// writable, every capability is dropped and slirp4netns publishes the game
+// ports from the private network namespace to the host. The sandbox dies
+// with the manager, it cannot be adopted like a plain process.
+type namespaceRuntime struct {
+	bwrap string
+	slirp string
+}
+
+func NewNamespaceRuntime() ServerRuntime {
+	return &namespaceRuntime{bwrap: "bwrap", slirp: "slirp4netns"}
+}
+
+func (r *namespaceRuntime) Start(spec RuntimeSpec) (ServerProcess, error) {
+	dir, err := filepath.Abs(spec.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	infoR, infoW, err := os.Pipe()
+	if err != nil {
+		return nil, err
+	}
+	defer infoR.Close()
+
+	cmd := exec.Command(r.bwrap,
+		"--unshare-user", "--unshare-pid", "--unshare-ipc", "--unshare-uts",
+		"--unshare-net", "--unshare-cgroup-try",
+		"--die-with-parent",
+		// bedrock_server itself is pid 1 of the sandbox, so the child-pid
+		// bwrap reports is the server and SIGTERM reaches its handler
+		"--as-pid-1",
+		"--ro-bind", "/usr", "/usr",
+		"--ro-bind-try", "/lib", "/lib",
+		"--ro-bind-try", "/lib64", "/lib64",
+		"--ro-bind-try", "/bin", "/bin",
+		"--ro-bind-try", "/etc/ssl", "/etc/ssl",
+		"--ro-bind-try", "/etc/resolv.conf", "/etc/resolv.conf",
+		"--bind", dir, "/server",
+		"--chdir", "/server",
+		"--proc", "/proc",
+		"--dev", "/dev",
+		"--tmpfs", "/tmp",
+		"--cap-drop", "ALL",
+		"--info-fd", "3",
+		"--", "./bedrock_server",
+	)
+	cmd.Dir = dir
+	cmd.ExtraFiles = []*os.File{infoW}
+
+	proc, err := startCmd(cmd, dir, spec.Cgroup)
+	infoW.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	// bwrap reports the pid of the sandboxed child, its network namespace
+	// is what slirp4netns has to attach to
+	var info struct {
+		ChildPid int `json:"child-pid"`
+	}
+	if err := json.NewDecoder(infoR).Decode(&info); err != nil {
+		proc.Signal(os.Kill)
+		proc.Wait()
+		return nil, fmt.Errorf("read bwrap info: %w", err)
+	}
+
+	sandbox := &sandboxProcess{execProcess: proc, child: info.ChildPid}
+	sandbox.net, err = r.startNetwork(info.ChildPid, dir, publishedPorts(spec))
+	if err != nil {
+		proc.Signal(os.Kill)
+		proc.Wait()
+		return nil, err
+	}
+	return sandbox, nil
+}
+
+func (r *namespaceRuntime) startNetwork(pid int, dir string, ports []int) (*exec.Cmd, error) {
+	socket := filepath.Join(dir, "slirp.sock")
+	os.Remove(socket)
+
+	cmd := exec.Command(r.slirp, "--configure", "--mtu=65520", "--disable-host-loopback",
+		"--api-socket", socket, strconv.Itoa(pid), "tap0")
+	detachProcess(cmd)
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start slirp4netns: %w", err)
+	}
+
+	for _, port := range ports {
+		if err := slirpHostForward(socket, port); err != nil {
+			cmd.Process.Kill()
+			cmd.Wait()
+			return nil, err
+		}
+	}
+	return cmd, nil
+}
+
+func slirpHostForward(socket string, port int) error {
+	var conn net.Conn
+	var err error
+	for i := 0; i < 50; i++ {
+		if conn, err = net.Dial("unix", socket); err == nil {
+			break
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	if err != nil {
+		return fmt.Errorf("slirp4netns api: %w", err)
+	}
+	defer conn.Close()
+
+	req := map[string]interface{}{
+		"execute": "add_hostfwd",
+		"arguments": map[string]interface{}{
+			"proto":      "udp",
+			"host_addr":  "0.0.0.0",
+			"host_port":  port,
+			"guest_port": port,
+		},
+	}
+	if err := json.NewEncoder(conn).Encode(req); err != nil {
+		return err
+	}
+
+	var resp struct {
+		Error *struct {
+			Desc string `json:"desc"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&resp); err != nil {
+		return fmt.Errorf("slirp4netns api: %w", err)
+	}
+	if resp.Error != nil {
+		return fmt.Errorf("publish udp port %d: %s", port, resp.Error.Desc)
+	}
+	return nil
+}
+
+// sandboxProcess is bwrap plus the slirp4netns helper that serves its network.
+type sandboxProcess struct {
+	*execProcess
+	net *exec.Cmd
+	// child is the host pid of bedrock_server inside the sandbox.
+	child int
+}
+
+// Signal sends graceful signals to bedrock_server, bwrap does not forward
+// them. Only a kill goes to bwrap, which takes the sandbox down with it.
+func (p *sandboxProcess) Signal(sig os.Signal) error {
+	if sig == os.Kill || p.child <= 0 {
+		return p.execProcess.Signal(sig)
+	}
+	child, err := os.FindProcess(p.child)
+	if err != nil {
+		return err
+	}
+	return child.Signal(sig)
+}
+
+func (p *sandboxProcess) forwarderPids() []int {
+	if p.net == nil || p.net.Process == nil {
+		return nil
+	}
+	return []int{p.net.Process.Pid}
+}
+
+func (p *sandboxProcess) Wait() (int, error) {
+	code, err := p.execProcess.Wait()
+	if p.net != nil {
+		p.net.Process.Kill()
+		p.net.Wait()
+	}
+	return code, err
+}
+
+// ociRuntime runs every world as a container through podman, optionally on a
+// specific low level OCI runtime such as runc or crun.
+type ociRuntime struct {
+	engine  string
+	runtime string
+	image   string
+}
+
+func NewOCIRuntime(engine, runtime, image string) ServerRuntime {
+	if engine == "" {
+		engine = "podman"
+	}
+	if image == "" {
+		image = "docker.io/library/ubuntu:24.04"
+	}
+	return &ociRuntime{engine: engine, runtime: runtime, image: image}
+}
+
+func containerName(world string) string {
+	return "bedrock-" + world
+}
+
+func (r *ociRuntime) Start(spec RuntimeSpec) (ServerProcess, error) {
+	dir, err := filepath.Abs(spec.Dir)
+	if err != nil {
+		return nil, err
+	}
+
+	name := containerName(spec.Name)
+	// a container left behind by a crashed manager would block the name
+	exec.Command(r.engine, "rm", "--force", name).Run()
+
+	args := []string{"run", "--rm", "--interactive", "--name", name}
+	if r.runtime != "" {
+		args = append(args, "--runtime", r.runtime)
+	}
+	args = append(args,
+		"--cap-drop", "ALL",
+		"--security-opt", "no-new-privileges",
+		"--read-only", "--tmpfs", "/tmp",
+		"--volume", dir+":/server:Z",
+		"--workdir", "/server",
+	)
+	for _, port := range publishedPorts(spec) {
+		args = append(args, "--publish", fmt.Sprintf("%d:%d/udp", port, port))
+	}
+	// the container lives in a cgroup of the engine, not in spec.Cgroup
+	if spec.Limits.CPUQuota > 0 {
+		args = append(args, "--cpus", strconv.FormatFloat(float64(spec.Limits.CPUQuota)/100, 'f', 2, 64))
+	}
+	if spec.Limits.MemoryMaxMB > 0 {
+		args = append(args, "--memory", fmt.Sprintf("%dm", spec.Limits.MemoryMaxMB))
+	}
+	if spec.Limits.PidsMax > 0 {
+		args = append(args, "--pids-limit", strconv.Itoa(spec.Limits.PidsMax))
+	}
+	args = append(args, r.image, "./bedrock_server")
+
+	cmd := exec.Command(r.engine, args...)
+	cmd.Dir = dir
+
+	proc, err := startCmd(cmd, dir, "")
+	if err != nil {
+		return nil, err
+	}
+	return &ociProcess{execProcess: proc, engine: r.engine, name: name}, nil
+}
+
+// ociProcess forwards signals through the engine, killing only the podman
+// client would leave the container running.
+type ociProcess struct {
+	*execProcess
+	engine string
+	name   string
+}
+
+// forwarderPids asks the engine for the conmon of the container, the port
+// forwarders of the engine run below it. An engine that does not answer
+// within inspectTimeout yields no pid.
+func (p *ociProcess) forwarderPids() []int {
+	ctx, cancel := context.WithTimeout(context.Background(), inspectTimeout)
+	defer cancel()
+	output, err := exec.CommandContext(ctx, p.engine, "inspect", "--format", "{{.State.ConmonPid}}", p.name).Output()
+	if err != nil {
+		return nil
+	}
+	pid, err := strconv.Atoi(strings.TrimSpace(string(output)))
+	if err != nil || pid <= 0 {
+		return nil
+	}
+	return []int{pid}
+}
+
+func (p *ociProcess) Signal(sig os.Signal) error {
+	if sig == os.Kill {
+		exec.Command(p.engine, "kill", "--signal", "KILL", p.name).Run()
+		return p.execProcess.Signal(sig)
+	}
+	return p.execProcess.Signal(sig)
+}
@@ -0,0 +1,351 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"minecrat_go/model"
+	"time"
+
+	"github.com/robfig/cron/v3"
+)
+
+const (
+	ScheduleRestart = "restart"
+	ScheduleStop    = "stop"
+	ScheduleStart   = "start"
+
+	defaultWarnSeconds = 300
+	maxWarnSeconds     = 3600
+)
+
+// countdownMarks are the seconds before a scheduled restart or stop at which
+// players get a warning.
+var countdownMarks = []int{1800, 900, 600, 300, 120, 60, 30, 10, 5, 4, 3, 2, 1}
+
+// leadSchedule fires lead earlier than the cron expression, so the countdown
+// of a restart ends right at the configured time.
+type leadSchedule struct {
+	cron.Schedule
+	lead time.Duration
+}
+
+func (s leadSchedule) Next(t time.Time) time.Time {
+	return s.Schedule.Next(t.Add(s.lead)).Add(-s.lead)
+}
+
+type scheduleEntry struct {
+	id    cron.EntryID
+	world string
+	lead  time.Duration
+}
+
+func parseSchedule(s *model.WorldSchedule) (cron.Schedule, error) {
+	if s.Action != ScheduleRestart && s.Action != ScheduleStop && s.Action != ScheduleStart {
+		return nil, fmt.Errorf("%w: action must be restart, stop or start", utils.ErrInvalidSched)
+	}
+	if s.WarnSeconds < 0 || s.WarnSeconds > maxWarnSeconds {
+		return nil, fmt.Errorf("%w: warn_seconds must be between 0 and %d", utils.ErrInvalidSched, maxWarnSeconds)
+	}
+	schedule, err := cron.ParseStandard(s.Cron)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", utils.ErrInvalidSched, err)
+	}
+	return schedule, nil
+}
+
+// LoadSchedules registers every enabled schedule from the database and starts
+// the cron runner, it is called once on boot.
+func (u *bedrockUC) LoadSchedules() error {
+	u.cron.Start()
+	// temporary bans and mutes end on the same runner, which also looks for
+	// hand edits of the permission and allowlist files
+	u.cron.AddFunc("@every 1m", u.expireBans)
+	u.cron.AddFunc("@every 1m", u.expireMutes)
+	u.cron.AddFunc("@every 5m", u.detectAccessDrift)
+
+	schedules, err := u.bedRepo.GetEnabledSchedules()
+	if err != nil {
+		return err
+	}
+
+	for _, s := range schedules {
+		if s.WorldServer == nil {
+			continue
+		}
+		if err := u.registerSchedule(s.WorldServer.Name, s); err != nil {
+			log.Printf("server %s: schedule %d not loaded: %s", s.WorldServer.Name, s.ID, err)
+		}
+	}
+	return nil
+}
+
+func (u *bedrockUC) registerSchedule(world string, s model.WorldSchedule) error {
+	schedule, err := parseSchedule(&s)
+	if err != nil {
+		return err
+	}
+
+	u.scheduleMu.Lock()
+	defer u.scheduleMu.Unlock()
+
+	if entry, ok := u.schedules[s.ID]; ok {
+		u.cron.Remove(entry.id)
+		delete(u.schedules, s.ID)
+	}
+	if !s.Enabled {
+		return nil
+	}
+
+	var lead time.Duration
+	if s.Action != ScheduleStart {
+		lead = time.Duration(s.WarnSeconds) * time.Second
+	}
+	id := u.cron.Schedule(leadSchedule{Schedule: schedule, lead: lead}, cron.FuncJob(func() {
+		u.runSchedule(world, s, lead)
+	}))
+	u.schedules[s.ID] = scheduleEntry{id: id, world: world, lead: lead}
+	return nil
+}
+
+func (u *bedrockUC) unregisterSchedule(id uint) {
+	u.scheduleMu.Lock()
+	defer u.scheduleMu.Unlock()
+
+	if entry, ok := u.schedules[id]; ok {
+		u.cron.Remove(entry.id)
+		delete(u.schedules, id)
+	}
+}
+
+// unregisterWorldSchedules drops the cron entries of a deleted world, the
+// rows themselves go with the world through the foreign key.
+func (u *bedrockUC) unregisterWorldSchedules(world string) {
+	u.scheduleMu.Lock()
+	defer u.scheduleMu.Unlock()
+
+	for id, entry := range u.schedules {
+		if entry.world == world {
+			u.cron.Remove(entry.id)
+			delete(u.schedules, id)
+		}
+	}
+}
+
+func (u *bedrockUC) runSchedule(world string, s model.WorldSchedule, lead time.Duration) {
+	log.Printf("server %s: running scheduled %s (schedule %d)", world, s.Action, s.ID)
+
+	var err error
+	switch s.Action {
+	case ScheduleRestart:
+		if err = u.countdown(world, "restart", lead); err == nil {
+			_, err = u.RestartServer(world)
+		}
+	case ScheduleStop:
+		if err = u.countdown(world, "stop", lead); err == nil {
+			_, err = u.StopServer(world, 0)
+		}
+	case ScheduleStart:
+		_, err = u.StartServer(&dto.StartServerReq{Name: world, Wait: true})
+	}
+
+	var runErr string
+	if err != nil {
+		runErr = err.Error()
+		log.Printf("server %s: scheduled %s failed: %s", world, s.Action, err)
+	}
+	if err := u.bedRepo.SetScheduleRun(s.ID, time.Now(), runErr); err != nil {
+		log.Printf("server %s: save schedule run failed: %s", world, err)
+	}
+}
+
+// countdown warns the players of a running world before it goes down. It
+// gives up when the manager shuts down meanwhile.
+func (u *bedrockUC) countdown(world, action string, lead time.Duration) error {
+	if err := u.requireState(world, action, StateRunning); err != nil {
+		return err
+	}
+
+	end := time.Now().Add(lead)
+	for _, mark := range countdownMarks {
+		left := time.Duration(mark) * time.Second
+		if left > lead {
+			continue
+		}
+		if err := u.sleepUntil(world, action, end.Add(-left)); err != nil {
+			return err
+		}
+
+		msg := fmt.Sprintf("say Server will %s in %s", action, countdownText(mark))
+		if err := u.SendCommandforAPI(world, msg); err != nil {
+			log.Printf("server %s: countdown warning failed: %s", world, err)
+		}
+	}
+	return u.sleepUntil(world, action, end)
+}
+
+func (u *bedrockUC) sleepUntil(world, action string, at time.Time) error {
+	t := time.NewTimer(time.Until(at))
+	defer t.Stop()
+
+	select {
+	case <-t.C:
+		return nil
+	case <-u.shutdown:
+		return fmt.Errorf("%w: scheduled %s of server %s cancelled, the manager shuts down", utils.ErrInvalidState, action, world)
+	}
+}
+
+func countdownText(seconds int) string {
+	switch {
+	case seconds >= 120:
+		return fmt.Sprintf("%d minutes", seconds/60)
+	case seconds == 60:
+		return "1 minute"
+	case seconds == 1:
+		return "1 second"
+	default:
+		return fmt.Sprintf("%d seconds", seconds)
+	}
+}
+
+// RestartServer stops a running world gracefully and starts it again.
+func (u *bedrockUC) RestartServer(name string) (*dto.StartServerResult, error) {
+	if err := u.requireState(name, "restart", StateRunning); err != nil {
+		return nil, err
+	}
+	if _, err := u.StopServer(name, 0); err != nil {
+		return nil, err
+	}
+	return u.StartServer(&dto.StartServerReq{Name: name, Wait: true})
+}
+
+func (u *bedrockUC) toScheduleDTO(world string, s model.WorldSchedule) dto.Schedule {
+	resp := dto.Schedule{
+		ID:          s.ID,
+		World:       world,
+		Action:      s.Action,
+		Cron:        s.Cron,
+		WarnSeconds: s.WarnSeconds,
+		Enabled:     s.Enabled,
+		LastRunAt:   s.LastRunAt,
+		LastError:   s.LastError,
+	}
+
+	u.scheduleMu.Lock()
+	entry, ok := u.schedules[s.ID]
+	u.scheduleMu.Unlock()
+	if ok {
+		if next := u.cron.Entry(entry.id).Next; !next.IsZero() {
+			next = next.Add(entry.lead)
+			resp.NextRunAt = &next
+		}
+	}
+	return resp
+}
+
+func (u *bedrockUC) GetSchedules(world string) ([]dto.Schedule, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(world)
+	if err != nil {
+		return nil, fmt.Errorf("server %s not found", world)
+	}
+
+	schedules, err := u.bedRepo.GetSchedules(worlddb.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	resp := make([]dto.Schedule, 0, len(schedules))
+	for _, s := range schedules {
+		resp = append(resp, u.toScheduleDTO(world, s))
+	}
+	return resp, nil
+}
+
+func (u *bedrockUC) CreateSchedule(world string, req *dto.ScheduleReq) (*dto.Schedule, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(world)
+	if err != nil {
+		return nil, fmt.Errorf("server %s not found", world)
+	}
+
+	s := model.WorldSchedule{
+		WorldServerId: worlddb.ID,
+		Action:        req.Action,
+		Cron:          req.Cron,
+		WarnSeconds:   defaultWarnSeconds,
+		Enabled:       true,
+	}
+	if req.WarnSeconds != nil {
+		s.WarnSeconds = *req.WarnSeconds
+	}
+	if req.Enabled != nil {
+		s.Enabled = *req.Enabled
+	}
+	if _, err := parseSchedule(&s); err != nil {
+		return nil, err
+	}
+
+	if err := u.bedRepo.CreateSchedule(&s); err != nil {
+		return nil, err
+	}
+	if err := u.registerSchedule(world, s); err != nil {
+		return nil, err
+	}
+
+	resp := u.toScheduleDTO(world, s)
+	return &resp, nil
+}
+
+func (u *bedrockUC) UpdateSchedule(world string, id uint, req *dto.ScheduleReq) (*dto.Schedule, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(world)
+	if err != nil {
+		return nil, fmt.Errorf("server %s not found", world)
+	}
+
+	s, err := u.bedRepo.GetSchedule(worlddb.ID, id)
+	if err != nil {
+		return nil, fmt.Errorf("schedule %d not found", id)
+	}
+	if req.Action != "" {
+		s.Action = req.Action
+	}
+	if req.Cron != "" {
+		s.Cron = req.Cron
+	}
+	if req.WarnSeconds != nil {
+		s.WarnSeconds = *req.WarnSeconds
+	}
+	if req.Enabled != nil {
+		s.Enabled = *req.Enabled
+	}
+	if _, err := parseSchedule(s); err != nil {
+		return nil, err
+	}
+
+	if err := u.bedRepo.UpdateSchedule(s); err != nil {
+		return nil, err
+	}
+	if err := u.registerSchedule(world, *s); err != nil {
+		return nil, err
+	}
+
+	resp := u.toScheduleDTO(world, *s)
+	return &resp, nil
+}
+
+func (u *bedrockUC) DeleteSchedule(world string, id uint) error {
+	worlddb, err := u.bedRepo.GetWorldByName(world)
+	if err != nil {
+		return fmt.Errorf("server %s not found", world)
+	}
+	if _, err := u.bedRepo.GetSchedule(worlddb.ID, id); err != nil {
+		return fmt.Errorf("schedule %d not found", id)
+	}
+
+	if err := u.bedRepo.DeleteSchedule(worlddb.ID, id); err != nil {
+		return err
+	}
+	u.unregisterSchedule(id)
+	return nil
+}
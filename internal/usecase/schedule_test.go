@@ -0,0 +1,56 @@
+package usecase
+
+import (
+	"errors"
+	"minecrat_go/helper/utils"
+	"testing"
+	"time"
+)
+
+func TestCountdownCancelledOnShutdown(t *testing.T) {
+	fake := NewFakeRuntime()
+	u, _ := newTestUC(t, fake, testWorld("alpha", 1))
+	startWorld(t, u, "alpha")
+
+	errc := make(chan error, 1)
+	go func() { errc <- u.countdown("alpha", "restart", time.Hour) }()
+	time.Sleep(50 * time.Millisecond)
+
+	stopped := make(chan struct{})
+	go func() {
+		u.StopAll()
+		close(stopped)
+	}()
+
+	select {
+	case err := <-errc:
+		if !errors.Is(err, utils.ErrInvalidState) {
+			t.Errorf("countdown err = %v, want ErrInvalidState", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("countdown kept sleeping after StopAll")
+	}
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("StopAll blocked on the countdown")
+	}
+}
+
+func TestCountdownRuns(t *testing.T) {
+	fake := NewFakeRuntime()
+	u, _ := newTestUC(t, fake, testWorld("alpha", 1))
+	startWorld(t, u, "alpha")
+
+	begin := time.Now()
+	if err := u.countdown("alpha", "stop", 1500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if waited := time.Since(begin); waited < 1400*time.Millisecond {
+		t.Errorf("countdown returned after %s, want the whole lead", waited)
+	}
+
+	if err := u.countdown("beta", "stop", time.Second); err == nil {
+		t.Error("countdown on a stopped world succeeded")
+	}
+}
@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ServerEventKind tells what a line of bedrock_server output means.
+type ServerEventKind string
+
+const (
+	// KindOutput is any line without a more specific meaning.
+	KindOutput             ServerEventKind = "output"
+	KindVersion            ServerEventKind = "version"
+	KindPortBound          ServerEventKind = "port_bound"
+	KindPortOccupied       ServerEventKind = "port_occupied"
+	KindStarted            ServerEventKind = "started"
+	KindStopping           ServerEventKind = "stopping"
+	KindStopped            ServerEventKind = "stopped"
+	KindPlayerConnected    ServerEventKind = "player_connected"
+	KindPlayerDisconnected ServerEventKind = "player_disconnected"
+	KindPlayerSpawned      ServerEventKind = "player_spawned"
+	KindPlayerList         ServerEventKind = "player_list"
+	KindAutosave           ServerEventKind = "autosave"
+	KindCommandError       ServerEventKind = "command_error"
+	KindContentLog         ServerEventKind = "content_log"
+	KindError              ServerEventKind = "error"
+)
+
+// ServerEvent is one parsed line of bedrock_server output. Only the fields
+// that belong to Kind are set.
+type ServerEvent struct {
+	World   string
+	Kind    ServerEventKind
+	Time    time.Time
+	Level   string
+	Message string
+	Raw     string
+
+	Player  string
+	Xuid    string
+	Version string
+	// Port and IPVersion come with KindPortBound
+	Port      int
+	IPVersion int
+	// Online and Max come with KindPlayerList
+	Online int
+	Max    int
+	// Category is the [Tag] of a KindContentLog line
+	Category string
+
+	server *BedrockServer
+}
+
+var (
+	// [2024-06-12 10:00:00:123 INFO] message, older builds have no millis
+	logPrefix      = regexp.MustCompile(`^\[(\d{4}-\d{2}-\d{2} \d{2}:\d{2}:\d{2})(?:[:.](\d{1,3}))? ([A-Z]+)\] ?(.*)$`)
+	versionLine    = regexp.MustCompile(`^Version:? (\d+(?:\.\d+)+)`)
+	portLine       = regexp.MustCompile(`^IPv([46]) supported, port: (\d+)`)
+	connectLine    = regexp.MustCompile(`^Player connected: (.+?), xuid: (\d*)`)
+	disconnectLine = regexp.MustCompile(`^Player disconnected: (.+?), xuid: (\d*)`)
+	spawnLine      = regexp.MustCompile(`^Player Spawned: (.+?),? xuid: (\d*)`)
+	playerListLine = regexp.MustCompile(`^There are (\d+)/(\d+) players online:`)
+	categoryLine   = regexp.MustCompile(`^\[([^\]]+)\]`)
+)
+
+// parseServerLine turns a line of output into a ServerEvent. at is used when
+// the line has no timestamp of its own.
+func parseServerLine(world, line string, at time.Time) ServerEvent {
+	ev := ServerEvent{World: world, Kind: KindOutput, Time: at, Message: line, Raw: line}
+
+	if m := logPrefix.FindStringSubmatch(line); m != nil {
+		if t, err := time.ParseInLocation("2006-01-02 15:04:05", m[1], time.Local); err == nil {
+			millis, _ := strconv.Atoi(m[2])
+			ev.Time = t.Add(time.Duration(millis) * time.Millisecond)
+		}
+		ev.Level = m[3]
+		ev.Message = m[4]
+	}
+	msg := ev.Message
+
+	switch {
+	case strings.HasPrefix(msg, "Player connected:"):
+		if m := connectLine.FindStringSubmatch(msg); m != nil {
+			ev.Kind, ev.Player, ev.Xuid = KindPlayerConnected, m[1], m[2]
+		}
+	case strings.HasPrefix(msg, "Player disconnected:"):
+		if m := disconnectLine.FindStringSubmatch(msg); m != nil {
+			ev.Kind, ev.Player, ev.Xuid = KindPlayerDisconnected, m[1], m[2]
+		}
+	case strings.HasPrefix(msg, "Player Spawned:"):
+		if m := spawnLine.FindStringSubmatch(msg); m != nil {
+			ev.Kind, ev.Player, ev.Xuid = KindPlayerSpawned, m[1], m[2]
+		}
+	case msg == "Server started.":
+		ev.Kind = KindStarted
+	case msg == "Server stop requested." || msg == "Stopping server...":
+		ev.Kind = KindStopping
+	case msg == "Quit correctly":
+		ev.Kind = KindStopped
+	case strings.HasPrefix(msg, "Running AutoCompaction") || strings.HasPrefix(msg, "Auto save"):
+		ev.Kind = KindAutosave
+	case strings.Contains(msg, "Network port occupied"):
+		ev.Kind = KindPortOccupied
+	case strings.HasPrefix(msg, "Unknown command") || strings.HasPrefix(msg, "Syntax error") ||
+		strings.HasPrefix(msg, "No targets matched selector"):
+		ev.Kind = KindCommandError
+	default:
+		parseDetail(&ev)
+	}
+	return ev
+}
+
+func parseDetail(ev *ServerEvent) {
+	msg := ev.Message
+	if m := versionLine.FindStringSubmatch(msg); m != nil {
+		ev.Kind, ev.Version = KindVersion, m[1]
+		return
+	}
+	if m := portLine.FindStringSubmatch(msg); m != nil {
+		ev.Kind = KindPortBound
+		ev.IPVersion, _ = strconv.Atoi(m[1])
+		ev.Port, _ = strconv.Atoi(m[2])
+		return
+	}
+	if m := playerListLine.FindStringSubmatch(msg); m != nil {
+		ev.Kind = KindPlayerList
+		ev.Online, _ = strconv.Atoi(m[1])
+		ev.Max, _ = strconv.Atoi(m[2])
+		return
+	}
+	if ev.Level != "WARN" && ev.Level != "ERROR" {
+		return
+	}
+	// content log entries carry their source, e.g. [Scripting] or [Json]
+	if m := categoryLine.FindStringSubmatch(msg); m != nil {
+		ev.Kind, ev.Category = KindContentLog, m[1]
+		return
+	}
+	if ev.Level == "ERROR" {
+		ev.Kind = KindError
+	}
+}
+
+// serverEventBus hands every parsed line to the subscribers in the order
+// they subscribed. Handlers run on the scanner goroutine of the world, so
+// they see the lines of one world in order and must not block.
+type serverEventBus struct {
+	mu       sync.RWMutex
+	handlers []func(ServerEvent)
+}
+
+func (b *serverEventBus) subscribe(handler func(ServerEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *serverEventBus) publish(ev ServerEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handler := range b.handlers {
+		handler(ev)
+	}
+}
@@ -0,0 +1,168 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseServerLine(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+
+	tests := []struct {
+		name string
+		line string
+		want ServerEvent
+	}{
+		{
+			name: "connect",
+			line: "[2024-06-12 10:00:00:123 INFO] Player connected: Steve Jobs, xuid: 2535400000000001",
+			want: ServerEvent{Kind: KindPlayerConnected, Level: "INFO", Player: "Steve Jobs", Xuid: "2535400000000001"},
+		},
+		{
+			name: "connect without xuid",
+			line: "[2024-06-12 10:00:00:123 INFO] Player connected: Steve, xuid: ",
+			want: ServerEvent{Kind: KindPlayerConnected, Level: "INFO", Player: "Steve"},
+		},
+		{
+			name: "disconnect",
+			line: "[2024-06-12 10:00:00:123 INFO] Player disconnected: Steve, xuid: 2535400000000001, pfid: abc",
+			want: ServerEvent{Kind: KindPlayerDisconnected, Level: "INFO", Player: "Steve", Xuid: "2535400000000001"},
+		},
+		{
+			name: "spawn",
+			line: "[2024-06-12 10:00:00:123 INFO] Player Spawned: Steve xuid: 2535400000000001, pfid: abc",
+			want: ServerEvent{Kind: KindPlayerSpawned, Level: "INFO", Player: "Steve", Xuid: "2535400000000001"},
+		},
+		{
+			name: "version",
+			line: "[2024-06-12 10:00:00:123 INFO] Version: 1.21.90.4",
+			want: ServerEvent{Kind: KindVersion, Level: "INFO", Version: "1.21.90.4"},
+		},
+		{
+			name: "ipv4 port",
+			line: "[2024-06-12 10:00:00:123 INFO] IPv4 supported, port: 19132: Used for gameplay and LAN discovery",
+			want: ServerEvent{Kind: KindPortBound, Level: "INFO", Port: 19132, IPVersion: 4},
+		},
+		{
+			name: "ipv6 port",
+			line: "[2024-06-12 10:00:00:123 INFO] IPv6 supported, port: 19133: Used for gameplay",
+			want: ServerEvent{Kind: KindPortBound, Level: "INFO", Port: 19133, IPVersion: 6},
+		},
+		{
+			name: "port occupied",
+			line: "[2024-06-12 10:00:00:123 ERROR] Network port occupied, can't start server.",
+			want: ServerEvent{Kind: KindPortOccupied, Level: "ERROR"},
+		},
+		{
+			name: "started",
+			line: "[2024-06-12 10:00:00:123 INFO] Server started.",
+			want: ServerEvent{Kind: KindStarted, Level: "INFO"},
+		},
+		{
+			name: "stop requested",
+			line: "[2024-06-12 10:00:00:123 INFO] Server stop requested.",
+			want: ServerEvent{Kind: KindStopping, Level: "INFO"},
+		},
+		{
+			name: "stopping",
+			line: "[2024-06-12 10:00:00:123 INFO] Stopping server...",
+			want: ServerEvent{Kind: KindStopping, Level: "INFO"},
+		},
+		{
+			name: "stopped",
+			line: "[2024-06-12 10:00:00:123 INFO] Quit correctly",
+			want: ServerEvent{Kind: KindStopped, Level: "INFO"},
+		},
+		{
+			name: "player list",
+			line: "[2024-06-12 10:00:00:123 INFO] There are 2/10 players online:",
+			want: ServerEvent{Kind: KindPlayerList, Level: "INFO", Online: 2, Max: 10},
+		},
+		{
+			name: "autosave",
+			line: "[2024-06-12 10:00:00:123 INFO] Running AutoCompaction...",
+			want: ServerEvent{Kind: KindAutosave, Level: "INFO"},
+		},
+		{
+			name: "command error",
+			line: "[2024-06-12 10:00:00:123 ERROR] Unknown command: foo. Please check that the command exists",
+			want: ServerEvent{Kind: KindCommandError, Level: "ERROR"},
+		},
+		{
+			name: "content log warning",
+			line: "[2024-06-12 10:00:00:123 WARN] [Scripting] script is slow",
+			want: ServerEvent{Kind: KindContentLog, Level: "WARN", Category: "Scripting"},
+		},
+		{
+			name: "content log error",
+			line: "[2024-06-12 10:00:00:123 ERROR] [Json] bad file",
+			want: ServerEvent{Kind: KindContentLog, Level: "ERROR", Category: "Json"},
+		},
+		{
+			name: "info with a tag is plain output",
+			line: "[2024-06-12 10:00:00:123 INFO] [Server] hello",
+			want: ServerEvent{Kind: KindOutput, Level: "INFO"},
+		},
+		{
+			name: "error",
+			line: "[2024-06-12 10:00:00:123 ERROR] something broke",
+			want: ServerEvent{Kind: KindError, Level: "ERROR"},
+		},
+		{
+			name: "older build without millis",
+			line: "[2024-06-12 10:00:00 INFO] Server started.",
+			want: ServerEvent{Kind: KindStarted, Level: "INFO"},
+		},
+		{
+			name: "no prefix",
+			line: "NO LOG FILE! - setting up server logging...",
+			want: ServerEvent{Kind: KindOutput},
+		},
+		{
+			name: "no prefix still parsed",
+			line: "Player connected: Steve, xuid: 2535400000000001",
+			want: ServerEvent{Kind: KindPlayerConnected, Player: "Steve", Xuid: "2535400000000001"},
+		},
+		{
+			name: "no prefix version",
+			line: "Version 1.21.90.4",
+			want: ServerEvent{Kind: KindVersion, Version: "1.21.90.4"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := parseServerLine("alpha", tt.line, at)
+			got.Message, got.Time = "", time.Time{}
+
+			want := tt.want
+			want.World, want.Raw = "alpha", tt.line
+			if got != want {
+				t.Errorf("got  %+v\nwant %+v", got, want)
+			}
+		})
+	}
+}
+
+func TestParseServerLineTime(t *testing.T) {
+	at := time.Date(2026, 1, 2, 3, 4, 5, 0, time.Local)
+
+	tests := []struct {
+		line string
+		time time.Time
+		msg  string
+	}{
+		{"[2024-06-12 10:00:00:123 INFO] Server started.", time.Date(2024, 6, 12, 10, 0, 0, 123e6, time.Local), "Server started."},
+		{"[2024-06-12 10:00:00 INFO] Server started.", time.Date(2024, 6, 12, 10, 0, 0, 0, time.Local), "Server started."},
+		{"Server started.", at, "Server started."},
+	}
+	for _, tt := range tests {
+		ev := parseServerLine("alpha", tt.line, at)
+		if !ev.Time.Equal(tt.time) {
+			t.Errorf("%q: time = %s, want %s", tt.line, ev.Time, tt.time)
+		}
+		if ev.Message != tt.msg {
+			t.Errorf("%q: message = %q, want %q", tt.line, ev.Message, tt.msg)
+		}
+	}
+}
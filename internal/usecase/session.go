@@ -0,0 +1,243 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"minecrat_go/dto"
+	"minecrat_go/model"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// reasons a session ended with
+const (
+	SessionEndDisconnect = "disconnect"
+	SessionEndRejoin     = "rejoin"
+	SessionEndStop       = "stop"
+	SessionEndCrash      = "crash"
+	SessionEndManager    = "manager_restart"
+
+	defaultSessionPage = 50
+	maxSessionPage     = 500
+)
+
+// onlinePlayer is a roster entry, SessionID is 0 when the session row could
+// not be written.
+type onlinePlayer struct {
+	Xuid      string
+	Name      string
+	JoinedAt  time.Time
+	SessionID uint
+}
+
+func (u *bedrockUC) openSession(ev ServerEvent) {
+	server := ev.server
+	session := model.PlayerSession{
+		WorldServerId: server.Id,
+		Xuid:          ev.Xuid,
+		Name:          ev.Player,
+		JoinedAt:      ev.Time,
+	}
+	if err := u.bedRepo.CreateSession(&session); err != nil {
+		log.Printf("server %s: open session of %s failed: %s", ev.World, ev.Player, err)
+		return
+	}
+
+	server.PlayerMu.Lock()
+	defer server.PlayerMu.Unlock()
+	if player, ok := server.Online[ev.Xuid]; ok && player.JoinedAt.Equal(ev.Time) {
+		player.SessionID = session.ID
+		server.Online[ev.Xuid] = player
+	}
+}
+
+func (u *bedrockUC) closeSession(world string, player onlinePlayer, at time.Time, reason string) {
+	if player.SessionID == 0 {
+		return
+	}
+	seconds := int64(max(at.Sub(player.JoinedAt), 0) / time.Second)
+	if err := u.bedRepo.CloseSession(player.SessionID, at, seconds, reason); err != nil {
+		log.Printf("server %s: close session of %s failed: %s", world, player.Name, err)
+	}
+}
+
+// endSessions empties the roster of a server that exited and closes the
+// sessions of everyone still on it.
+func (u *bedrockUC) endSessions(world string, server *BedrockServer, at time.Time, reason string) {
+	server.PlayerMu.Lock()
+	players := make([]onlinePlayer, 0, len(server.Online))
+	for xuid, player := range server.Online {
+		players = append(players, player)
+		delete(server.Online, xuid)
+	}
+	server.PlayerMu.Unlock()
+
+	for _, player := range players {
+		u.events.publish(world, dto.EventPlayerLeft, dto.PlayerEvent{Name: player.Name, Xuid: player.Xuid})
+		u.closeSession(world, player, at, reason)
+	}
+}
+
+// restoreSessions runs on boot after the orphans are handled. Open sessions
+// of an adopted server go back into its roster, the rest ended while the
+// manager was down and are closed at the last output of their world.
+func (u *bedrockUC) restoreSessions() {
+	sessions, err := u.bedRepo.GetOpenSessions()
+	if err != nil {
+		log.Printf("restore player sessions failed: %s", err)
+		return
+	}
+
+	for _, s := range sessions {
+		if s.WorldServer == nil {
+			continue
+		}
+		world := s.WorldServer.Name
+		player := onlinePlayer{Xuid: s.Xuid, Name: s.Name, JoinedAt: s.JoinedAt, SessionID: s.ID}
+
+		u.s.RLock()
+		server, ok := u.servers[world]
+		u.s.RUnlock()
+		if ok && server.Adopted {
+			server.PlayerMu.Lock()
+			server.Online[s.Xuid] = player
+			server.PlayerMu.Unlock()
+			continue
+		}
+		u.closeSession(world, player, lastOutputAt(world, s.JoinedAt), SessionEndManager)
+	}
+
+	// adopted worlds print no Server started., their idle timer starts once
+	// the roster is back
+	u.s.RLock()
+	adopted := make(map[string]*BedrockServer)
+	for world, server := range u.servers {
+		if server.Adopted {
+			adopted[world] = server
+		}
+	}
+	u.s.RUnlock()
+	for world, server := range adopted {
+		u.armIdle(world, server)
+	}
+}
+
+// lastOutputAt is when a world last printed anything, at the earliest since.
+func lastOutputAt(world string, since time.Time) time.Time {
+	info, err := os.Stat(filepath.Join(logDir(world), activeLogFile))
+	if err != nil || info.ModTime().Before(since) {
+		return since
+	}
+	return info.ModTime()
+}
+
+func (u *bedrockUC) GetOnlinePlayers(name string) ([]dto.OnlinePlayer, error) {
+	if _, err := u.bedRepo.GetWorldByName(name); err != nil {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+
+	u.s.RLock()
+	server, ok := u.servers[name]
+	u.s.RUnlock()
+
+	response := []dto.OnlinePlayer{}
+	if !ok {
+		return response, nil
+	}
+
+	now := time.Now()
+	server.PlayerMu.Lock()
+	for _, player := range server.Online {
+		response = append(response, dto.OnlinePlayer{
+			Xuid:     player.Xuid,
+			Name:     player.Name,
+			JoinedAt: player.JoinedAt,
+			Seconds:  int64(max(now.Sub(player.JoinedAt), 0) / time.Second),
+		})
+	}
+	server.PlayerMu.Unlock()
+
+	sort.Slice(response, func(i, j int) bool {
+		return response[i].JoinedAt.Before(response[j].JoinedAt)
+	})
+	return response, nil
+}
+
+func (u *bedrockUC) GetPlayerSessions(name, xuid string, limit, offset int) (*dto.SessionPage, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+	if xuid, err = u.playerXuid(xuid); err != nil {
+		return nil, err
+	}
+
+	if limit <= 0 {
+		limit = defaultSessionPage
+	}
+	limit = min(limit, maxSessionPage)
+	offset = max(offset, 0)
+
+	sessions, total, err := u.bedRepo.GetSessions(worlddb.ID, xuid, limit, offset)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	page := &dto.SessionPage{Sessions: make([]dto.PlayerSession, 0, len(sessions)), Total: total, Offset: offset, Limit: limit}
+	for _, s := range sessions {
+		session := dto.PlayerSession{
+			ID:        s.ID,
+			Xuid:      s.Xuid,
+			Name:      s.Name,
+			JoinedAt:  s.JoinedAt,
+			LeftAt:    s.LeftAt,
+			Seconds:   s.Seconds,
+			EndReason: s.EndReason,
+		}
+		if s.LeftAt == nil {
+			session.Seconds = int64(max(now.Sub(s.JoinedAt), 0) / time.Second)
+		}
+		page.Sessions = append(page.Sessions, session)
+	}
+	return page, nil
+}
+
+// GetPlaytime totals the playtime of every player of a world, the current
+// stay of online players included.
+func (u *bedrockUC) GetPlaytime(name string) ([]dto.Playtime, error) {
+	worlddb, err := u.bedRepo.GetWorldByName(name)
+	if err != nil {
+		return nil, fmt.Errorf("server %s not found", name)
+	}
+
+	playtime, err := u.bedRepo.GetPlaytime(worlddb.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	online, err := u.GetOnlinePlayers(name)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	current := make(map[string]dto.OnlinePlayer, len(online))
+	for _, player := range online {
+		current[player.Xuid] = player
+	}
+	for i := range playtime {
+		if player, ok := current[playtime[i].Xuid]; ok {
+			playtime[i].Seconds += player.Seconds
+			playtime[i].Name = player.Name
+			playtime[i].LastSeen = now
+			playtime[i].Online = true
+		}
+	}
+
+	sort.SliceStable(playtime, func(i, j int) bool {
+		return playtime[i].Seconds > playtime[j].Seconds
+	})
+	return playtime, nil
+}
@@ -0,0 +1,86 @@
+package usecase
+
+import (
+	"fmt"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"time"
+)
+
+type ServerState string
+
+const (
+	StateStopped  ServerState = "stopped"
+	StateStarting ServerState = "starting"
+	StateRunning  ServerState = "running"
+	StateStopping ServerState = "stopping"
+	StateCrashed  ServerState = "crashed"
+)
+
+var stateTransitions = map[ServerState][]ServerState{
+	StateStopped:  {StateStarting},
+	StateStarting: {StateRunning, StateStopping, StateCrashed},
+	StateRunning:  {StateStopping, StateCrashed},
+	StateStopping: {StateStopped},
+	StateCrashed:  {StateStarting, StateStopped},
+}
+
+func (s ServerState) canTransition(to ServerState) bool {
+	for _, next := range stateTransitions[s] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+func stateError(name string, state ServerState, action string) error {
+	return fmt.Errorf("%w: cannot %s server %s while it is %s", utils.ErrInvalidState, action, name, state)
+}
+
+// transition moves the world to the next state, the caller must hold s.mu.
+func (s *supervisor) transition(to ServerState) bool {
+	if !s.state.canTransition(to) {
+		return false
+	}
+	from := s.state
+	s.state = to
+	s.stateSince = time.Now()
+	if s.events != nil {
+		s.events.publish(s.name, dto.EventStateChange, dto.StateEvent{From: string(from), To: string(to)})
+	}
+	return true
+}
+
+func (s *supervisor) setState(to ServerState) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.transition(to)
+}
+
+// stateOf returns the lifecycle state of a world, worlds that were never
+// started in this manager are stopped.
+func (u *bedrockUC) stateOf(name string) (ServerState, *time.Time) {
+	u.s.RLock()
+	sup, ok := u.supervisors[name]
+	u.s.RUnlock()
+	if !ok {
+		return StateStopped, nil
+	}
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+	since := sup.stateSince
+	return sup.state, &since
+}
+
+// requireState rejects an action unless the world is in one of the given states.
+func (u *bedrockUC) requireState(name, action string, allowed ...ServerState) error {
+	state, _ := u.stateOf(name)
+	for _, a := range allowed {
+		if state == a {
+			return nil
+		}
+	}
+	return stateError(name, state, action)
+}
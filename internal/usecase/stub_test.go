@@ -0,0 +1,312 @@
+package usecase
+
+import (
+	"fmt"
+	"minecrat_go/internal/repository"
+	"minecrat_go/model"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// stubRepo keeps the rows the lifecycle touches in memory. Methods it does
+// not implement panic through the nil embedded interface.
+type stubRepo struct {
+	repository.BedrockRepo
+
+	mu        sync.Mutex
+	worlds    map[string]*model.WorldServer
+	sessions  map[uint]*model.PlayerSession
+	players   map[string]*model.Player
+	perms     map[uint][]model.Permission
+	allowlist map[uint][]model.AllowlistEntry
+	bans      map[string]*model.Ban
+	columns   map[uint]map[string]interface{}
+	nextId    uint
+}
+
+func newStubRepo(worlds ...model.WorldServer) *stubRepo {
+	r := &stubRepo{
+		worlds:    make(map[string]*model.WorldServer),
+		sessions:  make(map[uint]*model.PlayerSession),
+		players:   make(map[string]*model.Player),
+		perms:     make(map[uint][]model.Permission),
+		allowlist: make(map[uint][]model.AllowlistEntry),
+		bans:      make(map[string]*model.Ban),
+		columns:   make(map[uint]map[string]interface{}),
+	}
+	for i := range worlds {
+		world := worlds[i]
+		r.worlds[strings.ToLower(world.Name)] = &world
+	}
+	return r
+}
+
+func (r *stubRepo) GetWorldByName(name string) (*model.WorldServer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	world, ok := r.worlds[strings.ToLower(name)]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copy := *world
+	return &copy, nil
+}
+
+func (r *stubRepo) GetWorldPorts() ([]model.WorldServer, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var worlds []model.WorldServer
+	for _, world := range r.worlds {
+		worlds = append(worlds, *world)
+	}
+	return worlds, nil
+}
+
+// UpdateWorldProperties keeps the columns apart from the worlds, the tests
+// read them back from columns.
+func (r *stubRepo) UpdateWorldProperties(id uint, updates map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.columns[id] == nil {
+		r.columns[id] = make(map[string]interface{})
+	}
+	for column, value := range updates {
+		r.columns[id][column] = value
+	}
+	return nil
+}
+
+func (r *stubRepo) GetPermissions(worldId uint) ([]model.Permission, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]model.Permission(nil), r.perms[worldId]...), nil
+}
+
+// ReplacePermissions fails on a repeated xuid like idx_permission_world_xuid.
+func (r *stubRepo) ReplacePermissions(worldId uint, permissions []model.Permission) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := make(map[string]bool)
+	for _, p := range permissions {
+		if seen[p.Xuid] {
+			return fmt.Errorf("duplicate entry %s for key idx_permission_world_xuid", p.Xuid)
+		}
+		seen[p.Xuid] = true
+	}
+	r.perms[worldId] = append([]model.Permission(nil), permissions...)
+	return nil
+}
+
+func (r *stubRepo) GetAllowlist(worldId uint) ([]model.AllowlistEntry, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]model.AllowlistEntry(nil), r.allowlist[worldId]...), nil
+}
+
+func (r *stubRepo) ReplaceAllowlist(worldId uint, entries []model.AllowlistEntry) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.allowlist[worldId] = append([]model.AllowlistEntry(nil), entries...)
+	return nil
+}
+
+func (r *stubRepo) EnsurePlayerExists(xuid string, worldId uint) error {
+	return nil
+}
+
+func (r *stubRepo) UpsertPlayer(xuid, gamertag string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.players[xuid] = &model.Player{Xuid: xuid, Gamertag: gamertag}
+	return nil
+}
+
+func (r *stubRepo) GetPlayer(xuid string) (*model.Player, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	player, ok := r.players[xuid]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copy := *player
+	return &copy, nil
+}
+
+func (r *stubRepo) GetPlayersByGamertag(gamertag string) ([]model.Player, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var players []model.Player
+	for _, player := range r.players {
+		if strings.EqualFold(player.Gamertag, gamertag) {
+			players = append(players, *player)
+		}
+	}
+	return players, nil
+}
+
+func (r *stubRepo) GetPlayersByPastGamertag(gamertag string) ([]model.Player, error) {
+	return nil, nil
+}
+
+func (r *stubRepo) CreateSession(session *model.PlayerSession) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextId++
+	session.ID = r.nextId
+	copy := *session
+	r.sessions[session.ID] = &copy
+	return nil
+}
+
+func (r *stubRepo) CloseSession(id uint, leftAt time.Time, seconds int64, reason string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if session, ok := r.sessions[id]; ok {
+		session.LeftAt = &leftAt
+	}
+	return nil
+}
+
+func (r *stubRepo) GetOpenSessions() ([]model.PlayerSession, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var sessions []model.PlayerSession
+	for _, session := range r.sessions {
+		if session.LeftAt == nil {
+			sessions = append(sessions, *session)
+		}
+	}
+	return sessions, nil
+}
+
+func (r *stubRepo) openSessions() int {
+	sessions, _ := r.GetOpenSessions()
+	return len(sessions)
+}
+
+// GetActiveBan finds the bans of the bans map, they apply on every world.
+func (r *stubRepo) GetActiveBan(xuid string, worldId uint, at time.Time) (*model.Ban, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	ban, ok := r.bans[xuid]
+	if !ok {
+		return nil, gorm.ErrRecordNotFound
+	}
+	copy := *ban
+	return &copy, nil
+}
+
+func (r *stubRepo) AddBanAudit(audit *model.BanAudit) error {
+	return nil
+}
+
+func (r *stubRepo) CreateModeration(action *model.ModerationAction) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextId++
+	action.ID = r.nextId
+	return nil
+}
+
+func (r *stubRepo) GetActiveMute(xuid string, worldId uint, at time.Time) (*model.ModerationAction, error) {
+	return nil, gorm.ErrRecordNotFound
+}
+
+func (r *stubRepo) GetPendingMutes(xuid string, worldId uint) ([]model.ModerationAction, error) {
+	return nil, nil
+}
+
+// newTestUC runs a usecase on a FakeRuntime inside a temporary working
+// directory holding one world directory per world. Launch probes no host
+// port, creates no cgroup and writes no log file.
+func newTestUC(t *testing.T, fake *FakeRuntime, worlds ...model.WorldServer) (*bedrockUC, *stubRepo) {
+	t.Helper()
+	t.Chdir(t.TempDir())
+	for _, world := range worlds {
+		dir := filepath.Join("data/servers", world.Name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		props := "server-name=" + world.Name + "\nallow-list=false\n"
+		if err := os.WriteFile(filepath.Join(dir, "server.properties"), []byte(props), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	repo := newStubRepo(worlds...)
+	u := NewBedrockUC(repo, fake).(*bedrockUC)
+	u.hooks = launchHooks{
+		checkPorts: func(name string, ports ...int) error { return nil },
+		portHolder: func(port int) string { return "" },
+		prepareCgroup: func(name string, limits cgroupLimits) (string, error) {
+			return "", nil
+		},
+		openLog: func(name string) (*worldLog, error) { return nil, nil },
+	}
+	u.startTimeout = 5 * time.Second
+	u.commandTimeout = 2 * time.Second
+	u.commandQuiet = 50 * time.Millisecond
+	u.commandGrace = 100 * time.Millisecond
+	u.stopTimeout = 200 * time.Millisecond
+	u.termTimeout = 200 * time.Millisecond
+	u.backoffBase = 10 * time.Millisecond
+	u.backoffMax = 50 * time.Millisecond
+	t.Cleanup(u.StopAll)
+	return u, repo
+}
+
+func testWorld(name string, id uint) model.WorldServer {
+	return model.WorldServer{
+		ID:                id,
+		Name:              name,
+		Port:              19132 + int(id)*2,
+		PortV6:            19133 + int(id)*2,
+		RestartPolicy:     RestartNever,
+		RestartMaxRetries: 3,
+		Runtime:           RuntimeProcess,
+	}
+}
+
+// eventually polls cond until it holds or a few seconds passed.
+func eventually(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// joinPlayer connects a player to a running world and waits for the roster
+// to list them.
+func joinPlayer(t *testing.T, u *bedrockUC, fake *FakeRuntime, world, name, xuid string) {
+	t.Helper()
+	fake.Process(world).Connect(name, xuid)
+	eventually(t, name+" to join "+world, func() bool {
+		players, _ := u.GetOnlinePlayers(world)
+		for _, player := range players {
+			if player.Name == name {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// sentCommands returns the lines a world received on its console. It runs a
+// list first, which is answered only once every earlier line was handled.
+func sentCommands(t *testing.T, u *bedrockUC, fake *FakeRuntime, world string) []string {
+	t.Helper()
+	if _, err := u.RunCommand(world, "list", 0); err != nil {
+		t.Fatal(err)
+	}
+	return fake.Process(world).Commands()
+}
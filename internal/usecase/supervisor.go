@@ -0,0 +1,333 @@
+package usecase
+
+import (
+	"fmt"
+	"log"
+	"minecrat_go/dto"
+	"minecrat_go/helper/utils"
+	"sync"
+	"time"
+)
+
+const (
+	RestartNever     = "never"
+	RestartOnFailure = "on-failure"
+	RestartAlways    = "always"
+)
+
+// supervisor outlives a single bedrock_server process: it keeps the restart
+// policy, the retry counter and the last exit of one world.
+type supervisor struct {
+	name        string
+	events      *eventStream
+	mu          sync.Mutex
+	req         dto.StartServerReq
+	runtime     string
+	limits      cgroupLimits
+	policy      string
+	maxRetries  int
+	retries     int
+	crashLoop   bool
+	stopping    bool
+	state       ServerState
+	stateSince  time.Time
+	nextRestart time.Time
+	lastExit    *dto.ExitInfo
+	// idleAfter stops the world once nobody played on it that long, 0 never.
+	idleAfter time.Duration
+
+	// cancel is closed to abort a pending restart.
+	cancel chan struct{}
+}
+
+func (s *supervisor) startReq() (dto.StartServerReq, string, cgroupLimits) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.req, s.runtime, s.limits
+}
+
+// beginStop flags the next exit as expected. A crashed world only has its
+// pending restart cancelled and reports cancelled=true.
+func (s *supervisor) beginStop(name string) (cancelled bool, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.state {
+	case StateStarting, StateRunning:
+		s.transition(StateStopping)
+	case StateCrashed:
+		cancelled = true
+		s.transition(StateStopped)
+	default:
+		return false, stateError(name, s.state, "stop")
+	}
+
+	s.stopping = true
+	s.nextRestart = time.Time{}
+	select {
+	case <-s.cancel:
+	default:
+		close(s.cancel)
+	}
+	return cancelled, nil
+}
+
+// resetSupervisor is called on every manual start: it moves the world to
+// starting, reloads its restart policy and clears the crash-loop state.
+func (u *bedrockUC) resetSupervisor(req *dto.StartServerReq) (*supervisor, error) {
+	if u.closing.Load() {
+		return nil, fmt.Errorf("%w: cannot start server %s while the manager shuts down", utils.ErrInvalidState, req.Name)
+	}
+
+	policy, maxRetries, runtime := RestartNever, 0, RuntimeProcess
+	var limits cgroupLimits
+	var idleAfter time.Duration
+	if world, err := u.bedRepo.GetWorldByName(req.Name); err == nil {
+		policy, maxRetries, runtime = world.RestartPolicy, world.RestartMaxRetries, world.Runtime
+		limits = cgroupLimits{CPUQuota: world.CPUQuota, MemoryMaxMB: world.MemoryMaxMB, PidsMax: world.PidsMax}
+		idleAfter = time.Duration(world.IdleShutdownMinutes) * time.Minute
+		if req.WorldId == 0 {
+			req.WorldId = world.ID
+		}
+		if req.Port == 0 {
+			req.Port = world.Port
+		}
+		if req.PortV6 == 0 {
+			req.PortV6 = world.PortV6
+		}
+	} else {
+		log.Printf("server %s: load restart policy failed: %s", req.Name, err)
+	}
+
+	u.s.Lock()
+	defer u.s.Unlock()
+
+	sup, ok := u.supervisors[req.Name]
+	if !ok {
+		sup = &supervisor{name: req.Name, events: u.events, state: StateStopped, cancel: make(chan struct{})}
+		u.supervisors[req.Name] = sup
+	}
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	if !sup.transition(StateStarting) {
+		return nil, stateError(req.Name, sup.state, "start")
+	}
+
+	// abort a restart that may still be waiting on its backoff
+	if sup.cancel != nil {
+		select {
+		case <-sup.cancel:
+		default:
+			close(sup.cancel)
+		}
+	}
+	sup.req = *req
+	sup.runtime = runtime
+	sup.limits = limits
+	sup.idleAfter = idleAfter
+	sup.policy = policy
+	sup.maxRetries = maxRetries
+	sup.retries = 0
+	sup.crashLoop = false
+	sup.stopping = false
+	sup.nextRestart = time.Time{}
+	sup.cancel = make(chan struct{})
+
+	return sup, nil
+}
+
+// supervise waits for the process to exit, records how it ended and applies
+// the restart policy of the world.
+func (u *bedrockUC) supervise(name string, server *BedrockServer, sup *supervisor) {
+	server.exitStatus, server.waitErr = server.Proc.Wait()
+	server.markReady(fmt.Errorf("server %s exited with code %d before it was ready", name, server.exitStatus))
+	server.stopIdle()
+
+	// give the scanner a moment to flush the final lines into Logs
+	select {
+	case <-server.scanDone:
+	case <-time.After(2 * time.Second):
+	}
+
+	u.s.Lock()
+	if u.servers[name] == server {
+		delete(u.servers, name)
+	}
+	u.s.Unlock()
+	u.removeProcState(name)
+
+	exit := &dto.ExitInfo{
+		Code:      server.exitStatus,
+		At:        time.Now(),
+		LastLines: server.tailLogs(20),
+	}
+	if server.waitErr != nil {
+		exit.Error = server.waitErr.Error()
+	}
+	if cgroupOOMKills(server.Cgroup) > 0 {
+		exit.Reason = dto.ExitReasonOOM
+		log.Printf("server %s was killed by the cgroup oom killer", name)
+	}
+	if !server.Adopted {
+		removeCgroup(server.Cgroup)
+	}
+
+	sup.mu.Lock()
+	exit.Expected = sup.stopping
+	sup.lastExit = exit
+	if exit.Expected {
+		sup.transition(StateStopped)
+	} else {
+		sup.transition(StateCrashed)
+	}
+	if time.Since(server.StartedAt) >= u.stableDuration {
+		sup.retries = 0
+	}
+	sup.mu.Unlock()
+
+	reason := SessionEndCrash
+	if exit.Expected {
+		reason = SessionEndStop
+	}
+	u.endSessions(name, server, exit.At, reason)
+
+	close(server.done)
+
+	if exit.Expected {
+		return
+	}
+	log.Printf("server %s exited unexpectedly with code %d", name, exit.Code)
+	u.events.publish(name, dto.EventCrash, exit)
+
+	u.restartLoop(name, sup, exit)
+}
+
+func (u *bedrockUC) restartLoop(name string, sup *supervisor, exit *dto.ExitInfo) {
+	for {
+		sup.mu.Lock()
+		if !shouldRestart(sup.policy, exit) {
+			sup.mu.Unlock()
+			return
+		}
+		if sup.retries >= sup.maxRetries {
+			sup.crashLoop = true
+			sup.mu.Unlock()
+			log.Printf("server %s is crash looping, giving up after %d restarts", name, sup.maxRetries)
+			return
+		}
+
+		delay := u.backoff(sup.retries)
+		sup.retries++
+		sup.nextRestart = time.Now().Add(delay)
+		cancel := sup.cancel
+		sup.mu.Unlock()
+
+		log.Printf("server %s restarting in %s (attempt %d)", name, delay, sup.retries)
+		select {
+		case <-cancel:
+			return
+		case <-time.After(delay):
+		}
+
+		sup.mu.Lock()
+		sup.nextRestart = time.Time{}
+		if u.closing.Load() || !sup.transition(StateStarting) {
+			sup.mu.Unlock()
+			return
+		}
+		sup.mu.Unlock()
+
+		_, err := u.launch(name, sup)
+		if err == nil {
+			return
+		}
+
+		log.Printf("server %s restart failed: %s", name, err)
+		exit = &dto.ExitInfo{Code: -1, Error: err.Error(), At: time.Now()}
+		sup.mu.Lock()
+		sup.lastExit = exit
+		sup.transition(StateCrashed)
+		sup.mu.Unlock()
+	}
+}
+
+func shouldRestart(policy string, exit *dto.ExitInfo) bool {
+	switch policy {
+	case RestartAlways:
+		return true
+	case RestartOnFailure:
+		return exit.Code != 0
+	default:
+		return false
+	}
+}
+
+func (u *bedrockUC) backoff(retries int) time.Duration {
+	delay := u.backoffBase
+	for i := 0; i < retries && delay < u.backoffMax; i++ {
+		delay *= 2
+	}
+	if delay > u.backoffMax {
+		delay = u.backoffMax
+	}
+	return delay
+}
+
+func (u *bedrockUC) GetServerStatus(name string) (*dto.ServerStatus, error) {
+	u.s.RLock()
+	sup, ok := u.supervisors[name]
+	server := u.servers[name]
+	u.s.RUnlock()
+
+	if !ok {
+		world, err := u.bedRepo.GetWorldByName(name)
+		if err != nil {
+			return nil, fmt.Errorf("server %s not found", name)
+		}
+		return &dto.ServerStatus{
+			Name:          name,
+			State:         string(StateStopped),
+			RestartPolicy: world.RestartPolicy,
+			MaxRetries:    world.RestartMaxRetries,
+		}, nil
+	}
+
+	// read the server before taking sup.mu, the idle timer locks in the
+	// opposite order
+	var online, portV4, portV6 int
+	var version string
+	var pending []string
+	if server != nil {
+		online = server.onlineCount()
+		version, portV4, portV6 = server.banner()
+		pending = u.pendingRestart(name, server)
+	}
+
+	sup.mu.Lock()
+	defer sup.mu.Unlock()
+
+	since := sup.stateSince
+	status := &dto.ServerStatus{
+		Name:           name,
+		State:          string(sup.state),
+		StateSince:     &since,
+		Running:        sup.state == StateRunning,
+		RestartPolicy:  sup.policy,
+		Restarts:       sup.retries,
+		MaxRetries:     sup.maxRetries,
+		CrashLoop:      sup.crashLoop,
+		LastExit:       sup.lastExit,
+		PlayersOnline:  online,
+		Version:        version,
+		PortV4:         portV4,
+		PortV6:         portV6,
+		PendingRestart: pending,
+	}
+	if !sup.nextRestart.IsZero() {
+		next := sup.nextRestart
+		status.NextRestartAt = &next
+	}
+	return status, nil
+}
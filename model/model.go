@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type User struct {
 	ID       uint   `gorm:"primaryKey"`
 	Username string `gorm:"unique;not null"`
@@ -8,9 +10,10 @@ type User struct {
 }
 
 type WorldServer struct {
-	ID                      uint   `gorm:"primaryKey"`
-	CreatorId               *uint  `gorm:"index"`
-	Port                    int    `gorm:"not null;unique"`
+	ID                      uint  `gorm:"primaryKey"`
+	CreatorId               *uint `gorm:"index"`
+	Port                    int   `gorm:"not null;unique"`
+	PortV6                  int
 	Name                    string `gorm:"not null; unique"`
 	GameMode                string `gorm:"default:survival"`
 	Difficult               string `gorm:"default:normal"`
@@ -19,11 +22,157 @@ type WorldServer struct {
 	SeedWorld               string
 	MaxPlayer               int    `gorm:"default:10"`
 	DefaultPermissionPlayer string `gorm:"default:member"`
+	RestartPolicy           string `gorm:"default:never"`
+	RestartMaxRetries       int    `gorm:"default:5"`
+	Autostart               bool   `gorm:"default:false"`
+	StartOrder              int    `gorm:"default:0"`
+	Runtime                 string `gorm:"default:process"`
+	CPUQuota                int
+	MemoryMaxMB             int
+	PidsMax                 int
+	IdleShutdownMinutes     int
+	// AccessImported is set once the permissions.json and allowlist.json a
+	// world had before the permission tables existed are in the database.
+	AccessImported bool
 
 	//fk
-	User       *User    `gorm:"foreignKey:CreatorId;constraint:OnDelete:SET NULL"`
-	MemberRole []Member `gorm:"foreignKey:WorldServerId;constraint:OnDelete:CASCADE"`
+	User        *User            `gorm:"foreignKey:CreatorId;constraint:OnDelete:SET NULL"`
+	MemberRole  []Member         `gorm:"foreignKey:WorldServerId;constraint:OnDelete:CASCADE"`
+	Schedules   []WorldSchedule  `gorm:"foreignKey:WorldServerId;constraint:OnDelete:CASCADE"`
+	Sessions    []PlayerSession  `gorm:"foreignKey:WorldServerId;constraint:OnDelete:CASCADE"`
+	Permissions []Permission     `gorm:"foreignKey:WorldServerId;constraint:OnDelete:CASCADE"`
+	Allowlist   []AllowlistEntry `gorm:"foreignKey:WorldServerId;constraint:OnDelete:CASCADE"`
+}
+
+type WorldSchedule struct {
+	ID            uint   `gorm:"primaryKey"`
+	WorldServerId uint   `gorm:"index;not null"`
+	Action        string `gorm:"not null"`
+	Cron          string `gorm:"not null"`
+	WarnSeconds   int
+	Enabled       bool
+	LastRunAt     *time.Time
+	LastError     string
+
+	WorldServer *WorldServer `gorm:"foreignKey:WorldServerId"`
+}
+
+// PlayerSession is one stay of a player on a world, LeftAt is nil while the
+// player is online.
+type PlayerSession struct {
+	ID            uint      `gorm:"primaryKey"`
+	WorldServerId uint      `gorm:"index:idx_session_world_xuid;not null"`
+	Xuid          string    `gorm:"index:idx_session_world_xuid;not null"`
+	Name          string    `gorm:"not null"`
+	JoinedAt      time.Time `gorm:"not null"`
+	LeftAt        *time.Time
+	Seconds       int64
+	EndReason     string
+
+	WorldServer *WorldServer `gorm:"foreignKey:WorldServerId"`
 }
+
+// Player is the global directory entry of an xbox account, Gamertag is the
+// latest name it connected with.
+type Player struct {
+	Xuid      string    `gorm:"primaryKey;size:32"`
+	Gamertag  string    `gorm:"index;not null"`
+	FirstSeen time.Time `gorm:"not null"`
+	LastSeen  time.Time `gorm:"not null"`
+
+	Names []PlayerName `gorm:"foreignKey:Xuid;references:Xuid;constraint:OnDelete:CASCADE"`
+}
+
+// PlayerName is one gamertag an account was seen with.
+type PlayerName struct {
+	ID        uint      `gorm:"primaryKey"`
+	Xuid      string    `gorm:"index:idx_player_name,unique;size:32;not null"`
+	Gamertag  string    `gorm:"index:idx_player_name,unique;index;not null"`
+	FirstSeen time.Time `gorm:"not null"`
+	LastSeen  time.Time `gorm:"not null"`
+}
+
+// Ban keeps a player off one world, or off every world when WorldServerId is
+// nil. A ban is active until it is lifted or ExpiresAt passes.
+type Ban struct {
+	ID            uint   `gorm:"primaryKey"`
+	Xuid          string `gorm:"index;size:32;not null"`
+	Gamertag      string
+	WorldServerId *uint `gorm:"index"`
+	Reason        string
+	IssuedById    *uint
+	IssuedBy      string
+	CreatedAt     time.Time
+	ExpiresAt     *time.Time
+	LiftedAt      *time.Time
+	LiftedBy      string
+	LiftReason    string
+	// AllowlistBackup holds the allowlist entries removed by the ban as json,
+	// they are put back when the ban ends.
+	AllowlistBackup string `gorm:"type:text"`
+
+	WorldServer *WorldServer `gorm:"foreignKey:WorldServerId;constraint:OnDelete:CASCADE"`
+	Audits      []BanAudit   `gorm:"foreignKey:BanId;constraint:OnDelete:CASCADE"`
+}
+
+// BanAudit is one thing that happened to a ban.
+type BanAudit struct {
+	ID      uint   `gorm:"primaryKey"`
+	BanId   uint   `gorm:"index;not null"`
+	Action  string `gorm:"not null"`
+	ActorId *uint
+	Actor   string
+	Detail  string
+	At      time.Time `gorm:"not null"`
+}
+
+// ModerationAction is one warning, mute, unmute or kick a player got on a
+// world. A mute is active until it is lifted or ExpiresAt passes.
+type ModerationAction struct {
+	ID            uint   `gorm:"primaryKey"`
+	Xuid          string `gorm:"index;size:32;not null"`
+	Gamertag      string
+	WorldServerId uint   `gorm:"index;not null"`
+	Action        string `gorm:"not null"`
+	Reason        string
+	ActorId       *uint
+	Actor         string
+	CreatedAt     time.Time
+	ExpiresAt     *time.Time
+	LiftedAt      *time.Time
+	// Pending is set on a mute while the server has not applied its current
+	// state yet, because the player was offline.
+	Pending bool
+
+	WorldServer *WorldServer `gorm:"foreignKey:WorldServerId;constraint:OnDelete:CASCADE"`
+}
+
+// Permission is the level a player gets on a world, permissions.json of the
+// world is rendered from these rows.
+type Permission struct {
+	ID            uint   `gorm:"primaryKey"`
+	WorldServerId uint   `gorm:"uniqueIndex:idx_permission_world_xuid;not null"`
+	Xuid          string `gorm:"uniqueIndex:idx_permission_world_xuid;index;size:32;not null"`
+	Permission    string `gorm:"not null"`
+	UpdatedAt     time.Time
+
+	WorldServer *WorldServer `gorm:"foreignKey:WorldServerId"`
+}
+
+// AllowlistEntry lets a player join a world, allowlist.json of the world is
+// rendered from these rows. Xuid is empty for a player bedrock matches by
+// name.
+type AllowlistEntry struct {
+	ID                 uint   `gorm:"primaryKey"`
+	WorldServerId      uint   `gorm:"index;not null"`
+	Xuid               string `gorm:"index;size:32"`
+	Name               string
+	IgnoresPlayerLimit bool
+	UpdatedAt          time.Time
+
+	WorldServer *WorldServer `gorm:"foreignKey:WorldServerId"`
+}
+
 type Member struct {
 	ID   uint `gorm:"primaryKey"`
 	Xuid string